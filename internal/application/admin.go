@@ -0,0 +1,58 @@
+package application
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/labstack/echo/v4"
+)
+
+// AdminAuth is middleware that only lets requests through that carry the ADMIN_TOKEN environment variable
+// as a bearer token. The admin endpoints are disabled when no token is configured.
+func (app *Application) AdminAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token := os.Getenv("ADMIN_TOKEN")
+		if token == "" {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "admin endpoints not configured",
+			})
+		}
+
+		provided, ok := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			log.Printf("Rejected admin request to %s from %s", c.Request().URL.Path, c.RealIP())
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "invalid admin token",
+			})
+		}
+
+		return next(c)
+	}
+}
+
+// collectionStatus is the state of a collection reported by RefreshStatus: the ref and revision it is served from,
+// followed by the fields of its most recent refresh report.
+type collectionStatus struct {
+	Ref      string `json:"ref,omitempty"`      // Ref the source reads, empty for the default branch
+	Revision string `json:"revision,omitempty"` // Commit SHA the served content was read at, empty if unknown
+	*contentmanager.RefreshReport
+}
+
+// RefreshStatus returns the ref and revision every collection is served from, along with the report of its most
+// recent refresh.
+func (app *Application) RefreshStatus(c echo.Context) error {
+	statuses := make(map[string]collectionStatus)
+	for _, collection := range app.ContentManager.All() {
+		statuses[collection.Name()] = collectionStatus{
+			Ref:           collection.Ref(),
+			Revision:      collection.ServedRevision(),
+			RefreshReport: collection.LastReport(),
+		}
+	}
+
+	return c.JSON(http.StatusOK, statuses)
+}
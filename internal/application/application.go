@@ -1,53 +1,180 @@
 package application
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 
+	"github.com/jgndev/jgn.dev/internal/config"
 	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/livereload"
 	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/labstack/echo/v4"
 )
 
-// Application represents the core structure of the application, managing blog post and cheatsheet content through dedicated managers.
+// Application represents the core structure of the application, managing every content collection through a ContentManager.
 type Application struct {
-	ContentManager    *contentmanager.ContentManager    // Manages blog post content
-	CheatsheetManager *contentmanager.CheatsheetManager // Manages cheatsheet content
+	Config         *config.Config                 // Runtime configuration of the site the application serves
+	ContentManager *contentmanager.ContentManager // Manages the content collections
+	site           site.Metadata                  // Metadata pages of the site are rendered with
+	views          map[string]CollectionViews     // Templates used to render each collection
+	reloads        *livereload.Broker             // Notifies browsers of content changes in watch mode, nil otherwise
+	jobs           *jobQueue                      // Refreshes requested by webhooks
+	deliveries     *deliveryLog                   // Recent GitHub webhook deliveries
+	previews       *previewSet                    // Pull request previews being served
+	ctx            context.Context                // Cancelled by Close to stop background work
+	cancel         context.CancelFunc
+	background     sync.WaitGroup // Background work such as pollers and watchers, waited for by Close
+	snapshotPath   string         // Where parsed content is persisted between restarts, empty to disable
+	bundle         string         // ID of the embedded content bundle being served, empty if content is read from the sources
 }
 
-// New initializes and returns a pointer to an Application instance, setting up content and cheatsheet managers.
-func New() *Application {
-	repoOwner := site.PostRepoOwner
-	if len(repoOwner) <= 0 {
-		log.Fatal("PostRepoOwner must be set in site.go to the account name that owns the repo on github.com")
+// NewContentManager returns a ContentManager with every configured collection registered but not yet loaded.
+// Returns an error if a collection's source cannot be set up.
+func NewContentManager(cfg *config.Config) (*contentmanager.ContentManager, error) {
+	cm := contentmanager.NewContentManager(contentmanager.NewClientPool(cfg.Site.Name, cfg.Cache.ResponseDir))
+
+	for _, collection := range cfg.Collections {
+		contentConfig, err := applySourceOverrides(cfg.ContentConfig(collection))
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure the %s collection: %w", collection.Name, err)
+		}
+
+		if _, err := cm.Add(contentConfig); err != nil {
+			return nil, fmt.Errorf("failed to configure the %s collection, check its source settings: %w", collection.Name, err)
+		}
+	}
+
+	return cm, nil
+}
+
+// New initializes and returns a pointer to an Application instance serving a single site, setting up and loading
+// every collection of the site. Returns an error if the collections cannot be set up.
+func New(cfg *config.Config) (*Application, error) {
+	app, err := newApplication(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	repoName := site.PostRepoName
-	if len(repoName) <= 0 {
-		log.Fatal("PostRepoName must be set in site.go to the repo name that has the posts on github.com")
+	// A binary built with an embedded bundle serves exactly the bundled content; webhook refreshes overlay it.
+	// Otherwise serve the last known good content immediately and reconcile it against the sources in the
+	// background, or block until the initial content is loaded.
+	switch {
+	case app.restoreBundle():
+	case app.restoreSnapshot():
+		app.background.Add(1)
+		go func() {
+			defer app.background.Done()
+			app.loadContent()
+		}()
+	default:
+		app.loadContent()
 	}
 
-	cm := contentmanager.NewContentManager(repoOwner, repoName)
-	if err := cm.RefreshContent(); err != nil {
-		log.Printf("Failed to load initial content: %v", err)
+	// Watch local content directories and live-reload open pages during authoring
+	if cfg.Features.Watch {
+		log.Println("Watch enabled, watching local content directories for changes")
+		app.site.LiveReload = true
+		app.reloads = livereload.NewBroker()
+		app.startWatching()
 	}
 
-	// Initialize cheatsheet manager
-	cheatsheetOwner := site.CheatsheetRepoOwner
-	if len(cheatsheetOwner) <= 0 {
-		log.Fatal("CheatsheetRepoOwner must be set in site.go to the account name that owns the cheatsheets repo on github.com")
+	// Check the sources for changes missed by webhooks
+	app.startPolling()
+
+	return app, nil
+}
+
+// Load initializes and returns a pointer to an Application instance for a single site whose collections are all
+// loaded from their sources before it returns. Unlike New, it neither restores snapshots or bundles nor starts
+// watchers and pollers, so commands rendering the content once, such as export, see complete and current content.
+// Returns an error if a collection cannot be set up or loaded, or if any of its files fails to load.
+func Load(cfg *config.Config) (*Application, error) {
+	app, err := newApplication(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collection := range app.ContentManager.All() {
+		report, err := collection.RefreshContent(app.ctx)
+		if err != nil {
+			app.Close()
+			return nil, fmt.Errorf("failed to load %s: %w", collection.Name(), err)
+		}
+
+		if failed := report.Failed(); len(failed) > 0 {
+			for _, file := range failed {
+				log.Printf("Failed to load %s: %s", file.Path, file.Error)
+			}
+			app.Close()
+			return nil, fmt.Errorf("failed to load %d %s files", len(failed), collection.Name())
+		}
 	}
 
-	cheatsheetName := site.CheatsheetRepoName
-	if len(cheatsheetName) <= 0 {
-		log.Fatal("CheatsheetRepoName must be set in site.go to the repo name that has the cheatsheets on github.com")
+	return app, nil
+}
+
+// newApplication returns an Application for a single site with every collection set up but not yet loaded.
+func newApplication(cfg *config.Config) (*Application, error) {
+	cm, err := NewContentManager(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	csm := contentmanager.NewCheatsheetManager(cheatsheetOwner, cheatsheetName)
-	if err := csm.RefreshContent(); err != nil {
-		log.Printf("Failed to load initial cheatsheets: %v", err)
+	views := make(map[string]CollectionViews)
+	for _, collection := range cfg.Collections {
+		views[collection.Name] = viewSets[collection.Views]
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &Application{
-		ContentManager:    cm,
-		CheatsheetManager: csm,
+		Config:         cfg,
+		ContentManager: cm,
+		site:           site.New(cfg.Site),
+		views:          views,
+		jobs:           newJobQueue(),
+		deliveries:     newDeliveryLog(cfg.Webhook.DeliveryTTL, cfg.Webhook.DeliveryHistory),
+		previews:       newPreviewSet(cfg.Previews.Secret, cfg.Previews.TokenTTL, cfg.Previews.Max),
+		ctx:            ctx,
+		cancel:         cancel,
+		snapshotPath:   cfg.Cache.SnapshotPath,
+	}, nil
+}
+
+// SiteContext is middleware that makes the site's metadata available to the templates rendering the request.
+func (app *Application) SiteContext(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.SetRequest(c.Request().WithContext(site.WithMetadata(c.Request().Context(), app.site)))
+		return next(c)
+	}
+}
+
+// ContentRevision is middleware that reports the commit each collection is served from in the X-Content-Revision
+// header, e.g. "posts=3f2a9c1, cheatsheets=9c1d0e4", so it is clear which content a response reflects.
+// Collections whose revision is unknown are left out.
+func (app *Application) ContentRevision(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var revisions []string
+		for _, collection := range app.ContentManager.All() {
+			if revision := collection.ServedRevision(); revision != "" {
+				revisions = append(revisions, collection.Name()+"="+revision)
+			}
+		}
+
+		if len(revisions) > 0 {
+			c.Response().Header().Set("X-Content-Revision", strings.Join(revisions, ", "))
+		}
+
+		return next(c)
 	}
 }
+
+// Close stops background work such as content watchers, pollers, live reload streams and refreshes in progress,
+// and waits for it to finish. Interrupted refreshes keep the previous version of the files not yet loaded.
+func (app *Application) Close() {
+	app.cancel()
+	app.background.Wait()
+}
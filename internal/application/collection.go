@@ -0,0 +1,141 @@
+package application
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/labstack/echo/v4"
+)
+
+// Routes is implemented by *echo.Echo and *echo.Group, so routes can be added at the root of the site or below a
+// prefix, such as a pull request preview's.
+type Routes interface {
+	GET(path string, h echo.HandlerFunc, m ...echo.MiddlewareFunc) *echo.Route
+}
+
+// RegisterCollectionRoutes adds the list, search, detail and asset routes of every content collection.
+func (app *Application) RegisterCollectionRoutes(r Routes) {
+	for _, collection := range app.ContentManager.All() {
+		config := collection.Config()
+
+		r.GET(config.RoutePrefix, app.collectionHandler(collection, app.CollectionList))
+		r.GET(config.SearchPath, app.collectionHandler(collection, app.CollectionSearch))
+		r.GET(config.RoutePrefix+"/:slug", app.collectionHandler(collection, app.CollectionDetail))
+		r.GET(config.RoutePrefix+"/:slug/*", app.collectionHandler(collection, app.CollectionAsset))
+	}
+}
+
+// collectionHandler returns the handler the factory creates for the collection. Requests to a preview with its own
+// copy of the collection are served by a handler for the copy instead.
+func (app *Application) collectionHandler(collection *contentmanager.Collection, handler func(*contentmanager.Collection) echo.HandlerFunc) echo.HandlerFunc {
+	live := handler(collection)
+
+	return func(c echo.Context) error {
+		if preview, exists := app.collectionFor(c, collection.Name()); exists && preview != collection {
+			return handler(preview)(c)
+		}
+		return live(c)
+	}
+}
+
+// collectionFor returns the named collection a request reads: the preview's copy for requests to the preview of
+// a pull request against the collection's repository, the live collection otherwise.
+func (app *Application) collectionFor(c echo.Context, name string) (*contentmanager.Collection, bool) {
+	if preview, ok := c.Get(previewKey).(*Preview); ok {
+		if collection, exists := preview.content.Get(name); exists {
+			return collection, true
+		}
+	}
+
+	return app.ContentManager.Get(name)
+}
+
+// collectionRoutes returns the routes the collection's pages link to.
+func collectionRoutes(collection *contentmanager.Collection) site.Collection {
+	config := collection.Config()
+	return site.Collection{Prefix: config.RoutePrefix, Search: config.SearchPath}
+}
+
+// CollectionList returns a handler that renders a list of all documents in the collection, sorted by date (newest first).
+func (app *Application) CollectionList(collection *contentmanager.Collection) echo.HandlerFunc {
+	views := app.views[collection.Name()]
+	routes := collectionRoutes(collection)
+
+	return func(c echo.Context) error {
+		// Get all documents (already sorted by date, newest first)
+		docs := collection.GetAll()
+
+		return views.List(docs, routes).Render(c.Request().Context(), c.Response().Writer)
+	}
+}
+
+// CollectionDetail returns a handler for the collection's /:slug route.
+// It fetches a document by slug and renders the detail page, or returns an error if not found.
+func (app *Application) CollectionDetail(collection *contentmanager.Collection) echo.HandlerFunc {
+	views := app.views[collection.Name()]
+	routes := collectionRoutes(collection)
+
+	return func(c echo.Context) error {
+		slug := c.Param("slug")
+
+		if slug == "" {
+			return c.String(http.StatusBadRequest, views.Label+" slug is required")
+		}
+
+		doc, exists := collection.GetBySlug(slug)
+		if !exists {
+			return c.String(http.StatusNotFound, views.Label+" not found")
+		}
+
+		return views.Detail(doc, routes).Render(c.Request().Context(), c.Response().Writer)
+	}
+}
+
+// CollectionAsset returns a handler for the collection's /:slug/* route, which serves the files of a document's
+// per-post folder, such as the images it links to.
+func (app *Application) CollectionAsset(collection *contentmanager.Collection) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		name, err := url.PathUnescape(c.Param("*"))
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid file name")
+		}
+
+		content, err := collection.Asset(c.Request().Context(), c.Param("slug"), name)
+		if errors.Is(err, contentmanager.ErrAssetNotFound) {
+			return c.String(http.StatusNotFound, "File not found")
+		}
+		if err != nil {
+			return err
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(name))
+		if contentType == "" {
+			contentType = http.DetectContentType([]byte(content))
+		}
+
+		return c.Blob(http.StatusOK, contentType, []byte(content))
+	}
+}
+
+// CollectionSearch returns a handler that searches the collection, processing the "q" query parameter and rendering results.
+func (app *Application) CollectionSearch(collection *contentmanager.Collection) echo.HandlerFunc {
+	views := app.views[collection.Name()]
+	routes := collectionRoutes(collection)
+
+	return func(c echo.Context) error {
+		query := c.QueryParam("q")
+
+		var results []contentmanager.Document
+
+		if query != "" {
+			results = collection.Search(query)
+		}
+
+		return views.Search(query, results, routes).Render(c.Request().Context(), c.Response().Writer)
+	}
+}
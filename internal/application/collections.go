@@ -0,0 +1,137 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/a-h/templ"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/jgndev/jgn.dev/internal/views/pages"
+)
+
+// CollectionViews holds the templates used to render the list, detail and search pages of a content collection.
+type CollectionViews struct {
+	Label  string // Singular, human-readable name of a document, e.g. "Post"
+	List   func(docs []contentmanager.Document, routes site.Collection) templ.Component
+	Detail func(doc contentmanager.Document, routes site.Collection) templ.Component
+	Search func(query string, results []contentmanager.Document, routes site.Collection) templ.Component
+}
+
+// viewSets maps the view names collections can be configured with to the templates that render them.
+var viewSets = map[string]CollectionViews{
+	"posts": {
+		Label:  "Post",
+		List:   pages.Posts,
+		Detail: pages.Post,
+		Search: pages.SearchPage,
+	},
+	"cheatsheets": {
+		Label:  "Cheatsheet",
+		List:   pages.Cheatsheets,
+		Detail: pages.Cheatsheet,
+		Search: pages.CheatsheetSearchPage,
+	},
+}
+
+// applySourceOverrides adjusts a collection's source from the environment, using the upper-cased collection name as prefix:
+//   - <NAME>_CONTENT_DIR reads the collection from a local directory, e.g. POSTS_CONTENT_DIR=../posts
+//   - <NAME>_SOURCE_TYPE selects the source type: github, github-archive, gitlab, gitea, http or local
+//   - <NAME>_ARCHIVE_URL overrides the tarball URL used by the github-archive source
+//   - <NAME>_BASE_URL sets the API base URL, e.g. of GitHub Enterprise, GitLab or Gitea, or the root URL of an http source
+//   - <NAME>_REPO_OWNER and <NAME>_REPO_NAME override the configured repository (or GitLab group and project)
+//   - <NAME>_SOURCE_TOKEN sets the access token, instead of GITHUB_TOKEN, GITLAB_TOKEN or GITEA_TOKEN
+//   - <NAME>_GITHUB_APP_ID, _GITHUB_APP_INSTALLATION_ID and _GITHUB_APP_PRIVATE_KEY (or _GITHUB_APP_PRIVATE_KEY_FILE)
+//     authenticate as a GitHub App installation instead of with GITHUB_TOKEN. The unprefixed GITHUB_APP_* variables
+//     apply to every github and github-archive collection that doesn't set its own.
+//
+// Returns an error if the GitHub App settings are incomplete or set for a source that is not read from GitHub.
+func applySourceOverrides(config contentmanager.CollectionConfig) (contentmanager.CollectionConfig, error) {
+	prefix := strings.ToUpper(config.Name)
+
+	if sourceType := os.Getenv(prefix + "_SOURCE_TYPE"); sourceType != "" {
+		log.Printf("%s_SOURCE_TYPE set, reading %s from a %s source", prefix, config.Name, sourceType)
+		config.Source.Type = sourceType
+	}
+
+	if archiveURL := os.Getenv(prefix + "_ARCHIVE_URL"); archiveURL != "" {
+		config.Source.ArchiveURL = archiveURL
+	}
+
+	if baseURL := os.Getenv(prefix + "_BASE_URL"); baseURL != "" {
+		config.Source.BaseURL = baseURL
+	}
+
+	if owner := os.Getenv(prefix + "_REPO_OWNER"); owner != "" {
+		config.Source.RepoOwner = owner
+	}
+
+	if name := os.Getenv(prefix + "_REPO_NAME"); name != "" {
+		config.Source.RepoName = name
+	}
+
+	if token := os.Getenv(prefix + "_SOURCE_TOKEN"); token != "" {
+		config.Source.Token = token
+	}
+
+	if dir := os.Getenv(prefix + "_CONTENT_DIR"); dir != "" {
+		log.Printf("%s_CONTENT_DIR set, reading %s from local directory %s", prefix, config.Name, dir)
+		config.Source.Type = contentmanager.SourceLocal
+		config.Source.Dir = dir
+	}
+
+	// GitHub App credentials only apply to sources read from GitHub, once the source type is final
+	github := config.Source.Type == contentmanager.SourceGitHub || config.Source.Type == contentmanager.SourceGitHubArchive
+	switch appID, variable := lookupEnv(prefix, "GITHUB_APP_ID"); {
+	case appID == "":
+	case github:
+		auth, err := githubAppAuth(prefix, appID, variable)
+		if err != nil {
+			return config, err
+		}
+		config.Source.GitHubAuth = auth
+	case variable != "GITHUB_APP_ID":
+		return config, fmt.Errorf("%s is set, but the %s collection is read from a %s source, not from GitHub", variable, config.Name, config.Source.Type)
+	}
+
+	return config, nil
+}
+
+// githubAppAuth reads the GitHub App installation a collection authenticates as from the environment.
+// appID is the value of the variable named appIDVariable. Returns an error if a setting is missing or invalid.
+func githubAppAuth(prefix, appID, appIDVariable string) (contentmanager.GitHubAuthConfig, error) {
+	privateKey, _ := lookupEnv(prefix, "GITHUB_APP_PRIVATE_KEY")
+	privateKeyPath, _ := lookupEnv(prefix, "GITHUB_APP_PRIVATE_KEY_FILE")
+	auth := contentmanager.GitHubAuthConfig{
+		PrivateKey:     privateKey,
+		PrivateKeyPath: privateKeyPath,
+	}
+
+	var err error
+	if auth.AppID, err = strconv.ParseInt(appID, 10, 64); err != nil {
+		return auth, fmt.Errorf("invalid %s %q: must be a number", appIDVariable, appID)
+	}
+
+	installationID, variable := lookupEnv(prefix, "GITHUB_APP_INSTALLATION_ID")
+	if installationID == "" {
+		return auth, fmt.Errorf("%s is set, but %s_GITHUB_APP_INSTALLATION_ID or GITHUB_APP_INSTALLATION_ID is not", appIDVariable, prefix)
+	}
+	if auth.InstallationID, err = strconv.ParseInt(installationID, 10, 64); err != nil {
+		return auth, fmt.Errorf("invalid %s %q: must be a number", variable, installationID)
+	}
+
+	log.Printf("Authenticating %s as GitHub App %d installation %d", strings.ToLower(prefix), auth.AppID, auth.InstallationID)
+	return auth, nil
+}
+
+// lookupEnv returns the environment variable <prefix>_<name>, falling back to <name> if it is not set,
+// together with the name of the variable the value was read from.
+func lookupEnv(prefix, name string) (value, variable string) {
+	if value := os.Getenv(prefix + "_" + name); value != "" {
+		return value, prefix + "_" + name
+	}
+	return os.Getenv(name), name
+}
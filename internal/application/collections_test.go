@@ -0,0 +1,122 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/labstack/echo/v4"
+)
+
+func TestApplySourceOverridesGitHubApp(t *testing.T) {
+	t.Setenv("GITHUB_APP_ID", "42")
+	t.Setenv("GITHUB_APP_INSTALLATION_ID", "7")
+
+	github := contentmanager.CollectionConfig{Name: "posts", Source: contentmanager.SourceConfig{Type: contentmanager.SourceGitHub}}
+	config, err := applySourceOverrides(github)
+	if err != nil {
+		t.Fatalf("applySourceOverrides() error = %v", err)
+	}
+	if config.Source.GitHubAuth.AppID != 42 || config.Source.GitHubAuth.InstallationID != 7 {
+		t.Errorf("GitHubAuth = %+v, want app 42 installation 7", config.Source.GitHubAuth)
+	}
+
+	// The unprefixed app settings are meant for GitHub sources only
+	for _, sourceType := range []string{contentmanager.SourceGitLab, contentmanager.SourceGitea, contentmanager.SourceHTTP} {
+		other := contentmanager.CollectionConfig{Name: "notes", Source: contentmanager.SourceConfig{Type: sourceType}}
+		config, err := applySourceOverrides(other)
+		if err != nil || config.Source.GitHubAuth.AppID != 0 {
+			t.Errorf("applySourceOverrides() of a %s source = %+v, %v, want no GitHub App", sourceType, config.Source.GitHubAuth, err)
+		}
+	}
+
+	// Local content directories take precedence over the app settings
+	t.Setenv("POSTS_CONTENT_DIR", t.TempDir())
+	if config, err := applySourceOverrides(github); err != nil || config.Source.GitHubAuth.AppID != 0 {
+		t.Errorf("applySourceOverrides() with POSTS_CONTENT_DIR = %+v, %v, want no GitHub App", config.Source.GitHubAuth, err)
+	}
+}
+
+func TestApplySourceOverridesGitHubAppErrors(t *testing.T) {
+	github := contentmanager.CollectionConfig{Name: "posts", Source: contentmanager.SourceConfig{Type: contentmanager.SourceGitHub}}
+	gitlab := contentmanager.CollectionConfig{Name: "posts", Source: contentmanager.SourceConfig{Type: contentmanager.SourceGitLab}}
+
+	tests := []struct {
+		name   string
+		env    map[string]string
+		config contentmanager.CollectionConfig
+		want   string
+	}{
+		{"missing installation", map[string]string{"GITHUB_APP_ID": "42"}, github, "GITHUB_APP_INSTALLATION_ID is not"},
+		{"invalid app ID", map[string]string{"POSTS_GITHUB_APP_ID": "app", "GITHUB_APP_INSTALLATION_ID": "7"}, github, `invalid POSTS_GITHUB_APP_ID "app"`},
+		{"invalid installation", map[string]string{"GITHUB_APP_ID": "42", "POSTS_GITHUB_APP_INSTALLATION_ID": "x"}, github, `invalid POSTS_GITHUB_APP_INSTALLATION_ID "x"`},
+		{"not a GitHub source", map[string]string{"POSTS_GITHUB_APP_ID": "42"}, gitlab, "not from GitHub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := applySourceOverrides(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("applySourceOverrides() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionPagesLinkToCollectionRoutes(t *testing.T) {
+	dir := t.TempDir()
+	post := "---\ntitle: Hello\nslug: hello\npublished: true\n---\nHello"
+	if err := os.WriteFile(filepath.Join(dir, "hello.md"), []byte(post), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := contentmanager.NewContentManager(nil)
+	collection, err := content.Add(contentmanager.CollectionConfig{
+		Name:        "notes",
+		RoutePrefix: "/notes",
+		SearchPath:  "/notes/find",
+		Source:      contentmanager.SourceConfig{Type: contentmanager.SourceLocal, Dir: dir},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collection.RefreshContent(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, views := range []string{"posts", "cheatsheets"} {
+		app := &Application{
+			ContentManager: content,
+			site:           site.Default,
+			views:          map[string]CollectionViews{"notes": viewSets[views]},
+		}
+		e := echo.New()
+		e.Use(app.SiteContext)
+		app.RegisterCollectionRoutes(e)
+
+		for target, want := range map[string]string{
+			"/notes":              `href="/notes/hello"`,
+			"/notes/find?q=hello": `href="/notes/hello"`,
+		} {
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			body := rec.Body.String()
+			if rec.Code != http.StatusOK || !strings.Contains(body, want) || !strings.Contains(body, `action="/notes/find"`) {
+				t.Errorf("%s views: GET %s = %d, want a link %s and a search form posting to /notes/find", views, target, rec.Code, want)
+			}
+			if strings.Contains(body, `href="/posts/`) || strings.Contains(body, `href="/cheatsheets/`) {
+				t.Errorf("%s views: GET %s links to another collection's documents", views, target)
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package application
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Delivery is a webhook delivery recorded in the delivery history.
+type Delivery struct {
+	ID         string          `json:"id"` // X-GitHub-Delivery header, or a generated ID if the sender didn't set one
+	Event      string          `json:"event"`
+	Repository string          `json:"repository,omitempty"`
+	Status     int             `json:"status"`             // HTTP status the delivery was answered with, 0 while processing
+	Result     string          `json:"result,omitempty"`   // Message or error the delivery was answered with
+	Job        string          `json:"job,omitempty"`      // Refresh job the delivery queued or was merged into
+	ReplayOf   string          `json:"replayOf,omitempty"` // Delivery this one re-ran, for replays
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Duration   time.Duration   `json:"duration"`
+	Payload    json.RawMessage `json:"payload,omitempty"` // Only included when a single delivery is requested
+
+	body   []byte
+	digest string // SHA-256 of the body, to recognize a payload replayed under another delivery ID
+}
+
+// deliveryLog remembers recent webhook deliveries, so redeliveries and replayed payloads don't refresh content
+// again, and keeps them browsable. Deliveries are forgotten once they are older than the TTL or the log is full.
+type deliveryLog struct {
+	sync.Mutex
+	ttl        time.Duration
+	max        int
+	deliveries map[string]*Delivery // Recorded deliveries keyed by ID
+	digests    map[string]string    // ID of the latest delivery of each payload, keyed by digest
+	order      []string             // IDs of the recorded deliveries, oldest first
+}
+
+// newDeliveryLog initializes and returns a pointer to an empty deliveryLog keeping at most max deliveries for ttl.
+func newDeliveryLog(ttl time.Duration, max int) *deliveryLog {
+	return &deliveryLog{
+		ttl:        ttl,
+		max:        max,
+		deliveries: make(map[string]*Delivery),
+		digests:    make(map[string]string),
+	}
+}
+
+// newDelivery returns a delivery of the event with the given ID and body, received now.
+func newDelivery(id, event string, body []byte) *Delivery {
+	sum := sha256.Sum256(body)
+
+	var payload struct {
+		Repository WebhookRepository `json:"repository"`
+	}
+	json.Unmarshal(body, &payload)
+
+	return &Delivery{
+		ID:         id,
+		Event:      event,
+		Repository: payload.Repository.FullName,
+		ReceivedAt: time.Now().UTC(),
+		body:       body,
+		digest:     hex.EncodeToString(sum[:]),
+	}
+}
+
+// newDeliveryID returns an ID for a delivery whose sender didn't identify it.
+func newDeliveryID() string {
+	return "local-" + newJobID()
+}
+
+// claim records the delivery unless a delivery with the same ID or payload was already recorded, in which case
+// that delivery is returned instead. Deliveries that failed with a server error can be delivered again.
+func (l *deliveryLog) claim(delivery *Delivery) *Delivery {
+	l.Lock()
+	defer l.Unlock()
+
+	l.prune()
+
+	previous, exists := l.deliveries[delivery.ID]
+	if !exists {
+		previous, exists = l.deliveries[l.digests[delivery.digest]]
+	}
+	if exists && previous.Status < http.StatusInternalServerError {
+		return previous
+	}
+
+	l.add(delivery)
+	return nil
+}
+
+// record records the delivery without checking for duplicates.
+func (l *deliveryLog) record(delivery *Delivery) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.prune()
+	l.add(delivery)
+}
+
+// add records the delivery, replacing any delivery with the same ID. The caller must hold the lock.
+func (l *deliveryLog) add(delivery *Delivery) {
+	if _, exists := l.deliveries[delivery.ID]; exists {
+		l.order = slices.DeleteFunc(l.order, func(id string) bool { return id == delivery.ID })
+	}
+
+	l.deliveries[delivery.ID] = delivery
+	l.digests[delivery.digest] = delivery.ID
+	l.order = append(l.order, delivery.ID)
+
+	for len(l.order) > l.max {
+		l.forget(l.order[0])
+	}
+}
+
+// prune forgets deliveries older than the TTL. The caller must hold the lock.
+func (l *deliveryLog) prune() {
+	cutoff := time.Now().Add(-l.ttl)
+	for len(l.order) > 0 && l.deliveries[l.order[0]].ReceivedAt.Before(cutoff) {
+		l.forget(l.order[0])
+	}
+}
+
+// forget removes the oldest delivery, which must have the given ID. The caller must hold the lock.
+func (l *deliveryLog) forget(id string) {
+	delivery := l.deliveries[id]
+	if l.digests[delivery.digest] == id {
+		delete(l.digests, delivery.digest)
+	}
+	delete(l.deliveries, id)
+	l.order = l.order[1:]
+}
+
+// finish records the response the delivery was answered with.
+func (l *deliveryLog) finish(delivery *Delivery, resp webhookResponse, duration time.Duration) {
+	var result struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+		Job     string `json:"job"`
+	}
+	if data, err := json.Marshal(resp.body); err == nil {
+		json.Unmarshal(data, &result)
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	delivery.Status = resp.status
+	delivery.Result = result.Message
+	if result.Error != "" {
+		delivery.Result = result.Error
+	}
+	delivery.Job = result.Job
+	delivery.Duration = duration
+}
+
+// get returns a copy of the delivery with the given ID, including its payload.
+func (l *deliveryLog) get(id string) (Delivery, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	delivery, exists := l.deliveries[id]
+	if !exists {
+		return Delivery{}, false
+	}
+
+	view := *delivery
+	if json.Valid(delivery.body) {
+		view.Payload = json.RawMessage(delivery.body)
+	}
+	return view, true
+}
+
+// list returns copies of the recorded deliveries without their payloads, newest first.
+func (l *deliveryLog) list() []Delivery {
+	l.Lock()
+	defer l.Unlock()
+
+	l.prune()
+
+	deliveries := make([]Delivery, 0, len(l.order))
+	for i := len(l.order) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *l.deliveries[l.order[i]])
+	}
+
+	return deliveries
+}
+
+// runDelivery processes the delivery's event with the given request headers and records how the delivery was
+// answered.
+func (app *Application) runDelivery(delivery *Delivery, header http.Header) webhookResponse {
+	start := time.Now()
+	resp := app.processWebhook(delivery.Event, header, delivery.body)
+	app.deliveries.finish(delivery, resp, time.Since(start))
+
+	return resp
+}
+
+// WebhookDeliveries lists the recent webhook deliveries with the result of each, newest first.
+func (app *Application) WebhookDeliveries(c echo.Context) error {
+	return c.JSON(http.StatusOK, app.deliveries.list())
+}
+
+// WebhookDelivery returns a recorded webhook delivery, including its payload.
+func (app *Application) WebhookDelivery(c echo.Context) error {
+	delivery, exists := app.deliveries.get(c.Param("id"))
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "delivery not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// ReplayWebhookDelivery runs a recorded webhook delivery again, e.g. to retry a refresh after fixing a source,
+// and returns the new delivery. Replays skip signature verification and duplicate detection, so the endpoint
+// must only be reachable by operators. This is the way to process a recorded payload again, since the webhook
+// endpoint rejects it under a new delivery ID.
+func (app *Application) ReplayWebhookDelivery(c echo.Context) error {
+	original, exists := app.deliveries.get(c.Param("id"))
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "delivery not found",
+		})
+	}
+
+	if _, exists := webhookEvents[original.Event]; !exists {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "the site does not act on " + original.Event + " events",
+		})
+	}
+
+	replay := newDelivery(newDeliveryID(), original.Event, original.body)
+	replay.ReplayOf = original.ID
+	app.deliveries.record(replay)
+	log.Printf("Replaying %s delivery %s as %s", original.Event, original.ID, replay.ID)
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("X-GitHub-Event", original.Event)
+	header.Set("X-GitHub-Delivery", replay.ID)
+
+	// The handler's response is only recorded, the operator gets the replayed delivery
+	app.runDelivery(replay, header)
+
+	view, _ := app.deliveries.get(replay.ID)
+	view.Payload = nil
+	return c.JSON(http.StatusOK, view)
+}
@@ -0,0 +1,94 @@
+package application
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/labstack/echo/v4"
+)
+
+// webhookApp returns an Application without collections that accepts webhooks signed with "secret".
+func webhookApp() *Application {
+	cfg := &config.Config{}
+	cfg.Site.WebhookSecret = "secret"
+
+	return &Application{
+		Config:         cfg,
+		ContentManager: contentmanager.NewContentManager(nil),
+		jobs:           newJobQueue(),
+		deliveries:     newDeliveryLog(time.Hour, 10),
+	}
+}
+
+// deliver sends a signed GitHub webhook delivery to the application.
+func deliver(app *Application, event, id, body string) *httptest.ResponseRecorder {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", id)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	rec := httptest.NewRecorder()
+	app.WebhookHandler(echo.New().NewContext(req, rec))
+	return rec
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	app := webhookApp()
+	ping := `{"zen": "Keep it logically awesome.", "hook_id": 1, "repository": {"name": "posts", "full_name": "jgndev/posts"}}`
+
+	if rec := deliver(app, "ping", "d1", ping); rec.Code != http.StatusOK {
+		t.Fatalf("ping answered with %d, want 200", rec.Code)
+	}
+	if rec := deliver(app, "ping", "d1", ping); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "duplicate delivery ignored") {
+		t.Errorf("redelivery answered with %d %s, want the duplicate to be acknowledged", rec.Code, rec.Body)
+	}
+	if rec := deliver(app, "ping", "d2", ping); rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), `"delivery":"d1"`) {
+		t.Errorf("payload under a new delivery ID answered with %d %s, want 409 naming d1", rec.Code, rec.Body)
+	}
+	if rec := deliver(app, "star", "d3", `{"action": "created"}`); rec.Code != http.StatusNoContent {
+		t.Errorf("unhandled event answered with %d, want 204", rec.Code)
+	}
+
+	delivery, _ := app.deliveries.get("d1")
+	if delivery.Status != http.StatusOK || delivery.Result != "pong" || delivery.Repository != "jgndev/posts" {
+		t.Errorf("recorded delivery = %+v, want status 200 with result pong", delivery)
+	}
+}
+
+func TestReplayWebhookDelivery(t *testing.T) {
+	app := webhookApp()
+	deliver(app, "ping", "d1", `{"zen": "Design for failure.", "hook_id": 1}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/webhook/deliveries/d1/replay", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("d1")
+
+	if err := app.ReplayWebhookDelivery(c); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("ReplayWebhookDelivery() = %v with status %d, want 200", err, rec.Code)
+	}
+
+	var replay Delivery
+	if err := json.Unmarshal(rec.Body.Bytes(), &replay); err != nil {
+		t.Fatal(err)
+	}
+	if replay.ReplayOf != "d1" || replay.Status != http.StatusOK || replay.Result != "pong" {
+		t.Errorf("replay = %+v, want a processed replay of d1", replay)
+	}
+	if recorded, exists := app.deliveries.get(replay.ID); !exists || recorded.Result != "pong" {
+		t.Errorf("replay %s was not recorded with its result", replay.ID)
+	}
+}
@@ -1,6 +1,8 @@
 package application
 
 import (
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
 	"github.com/jgndev/jgn.dev/internal/views/pages"
 	"github.com/labstack/echo/v4"
 )
@@ -8,7 +10,12 @@ import (
 // Home handles the root route and renders the home page with the latest 6 posts.
 func (app *Application) Home(c echo.Context) error {
 	// Get recent posts (latest 6)
-	recentPosts := app.ContentManager.GetRecent(6)
+	var recentPosts []contentmanager.Post
+	routes := site.Collection{Prefix: "/posts", Search: "/search"}
+	if posts, exists := app.collectionFor(c, "posts"); exists {
+		recentPosts = posts.GetRecent(6)
+		routes = collectionRoutes(posts)
+	}
 
-	return pages.Home(recentPosts).Render(c.Request().Context(), c.Response().Writer)
+	return pages.Home(recentPosts, routes).Render(c.Request().Context(), c.Response().Writer)
 }
@@ -0,0 +1,209 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/labstack/echo/v4"
+)
+
+// maxFinishedJobs bounds how many finished refresh jobs are kept for the status endpoint.
+const maxFinishedJobs = 100
+
+// JobStatus is the state of a refresh job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"    // Waiting for the repository's previous job to finish
+	JobRunning   JobStatus = "running"   // Refreshing the collections
+	JobSucceeded JobStatus = "succeeded" // Every file was refreshed
+	JobPartial   JobStatus = "partial"   // Some files failed and keep their previous version
+	JobFailed    JobStatus = "failed"    // A collection could not be refreshed
+)
+
+// RefreshJob is a content refresh requested by one or more push or release webhooks for the same repository.
+// Deliveries received while the job is queued are merged into it.
+type RefreshJob struct {
+	ID          string                          `json:"id"`
+	Repository  string                          `json:"repository"`
+	Status      JobStatus                       `json:"status"`
+	Deliveries  int                             `json:"deliveries"`          // Number of webhook deliveries merged into the job
+	Ref         string                          `json:"ref,omitempty"`       // Release tag collections following releases are pinned to
+	PushedRef   string                          `json:"pushedRef,omitempty"` // Git ref the merged pushes updated
+	Collections []string                        `json:"collections"`         // Collections the job refreshes, once running
+	Completed   int                             `json:"completed"`           // Collections refreshed so far
+	Failed      int                             `json:"failed"`              // Files that failed to load
+	Reports     []*contentmanager.RefreshReport `json:"reports"`             // Per-file results of each refreshed collection
+	Error       string                          `json:"error,omitempty"`
+	CreatedAt   time.Time                       `json:"createdAt"`
+	StartedAt   *time.Time                      `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time                      `json:"finishedAt,omitempty"`
+
+	payload   GitHubWebhookPayload // Commits of every merged push, in delivery order
+	truncated bool                 // Whether any merged push did not list all its commits
+	pushed    bool                 // Whether any push was merged, as opposed to only releases
+}
+
+// jobQueue runs refresh jobs in the background, one job at a time per repository, so webhook requests return
+// before the refresh is done.
+type jobQueue struct {
+	sync.Mutex
+	jobs     map[string]*RefreshJob // Every known job keyed by ID
+	queued   map[string]*RefreshJob // Job waiting to run keyed by repository and pushed ref, merged with new deliveries
+	running  map[string]bool        // Keys of queued with a worker
+	finished []string               // IDs of finished jobs, oldest first
+}
+
+// newJobQueue initializes and returns a pointer to an empty jobQueue.
+func newJobQueue() *jobQueue {
+	return &jobQueue{
+		jobs:    make(map[string]*RefreshJob),
+		queued:  make(map[string]*RefreshJob),
+		running: make(map[string]bool),
+	}
+}
+
+// enqueueRefresh queues a refresh of the collections that read the pushed ref of the repository and returns a copy
+// of the job. If a job for the same repository and ref is already waiting to run, the push is merged into it instead.
+func (app *Application) enqueueRefresh(payload GitHubWebhookPayload, truncated bool) RefreshJob {
+	return app.enqueue(payload.Repository.Path()+" "+payload.Ref, payload.Repository.Path(), "push", func(job *RefreshJob) {
+		job.PushedRef = payload.Ref
+		job.payload.Ref = payload.Ref
+		job.payload.After = payload.After
+		job.payload.Repository = payload.Repository
+		job.payload.Commits = append(job.payload.Commits, payload.Commits...)
+		job.truncated = job.truncated || truncated
+		job.pushed = true
+	})
+}
+
+// enqueueRelease queues pinning the collections that follow the repository's releases to the released tag and
+// returns a copy of the job. If a job for the repository is already waiting to run, the release is merged into it,
+// so the latest release wins.
+func (app *Application) enqueueRelease(payload ReleasePayload) RefreshJob {
+	return app.enqueue(payload.Repository.Path(), payload.Repository.Path(), "release", func(job *RefreshJob) {
+		job.payload.Repository = payload.Repository
+		job.Ref = payload.Release.TagName
+	})
+}
+
+// enqueue merges a webhook delivery into the job waiting to run under the given key, or queues a new job for the
+// repository, and returns a copy of the job. Jobs with the same key run one at a time. merge records the delivery
+// in the job and is called with the queue lock held.
+func (app *Application) enqueue(key, repo, event string, merge func(job *RefreshJob)) RefreshJob {
+	q := app.jobs
+
+	q.Lock()
+	defer q.Unlock()
+
+	job, exists := q.queued[key]
+	if exists {
+		log.Printf("Merging %s to %s into queued refresh job %s", event, repo, job.ID)
+		merge(job)
+		job.Deliveries++
+		return job.view()
+	}
+
+	job = &RefreshJob{
+		ID:         newJobID(),
+		Repository: repo,
+		Status:     JobQueued,
+		Deliveries: 1,
+		CreatedAt:  time.Now().UTC(),
+	}
+	merge(job)
+	q.jobs[job.ID] = job
+	q.queued[key] = job
+	log.Printf("Queued refresh job %s for %s to %s", job.ID, event, repo)
+
+	if !q.running[key] {
+		q.running[key] = true
+		app.background.Add(1)
+		go func() {
+			defer app.background.Done()
+			app.runJobs(key)
+		}()
+	}
+
+	return job.view()
+}
+
+// runJobs runs the jobs queued under the key one after another, until none is left.
+func (app *Application) runJobs(key string) {
+	q := app.jobs
+
+	for {
+		q.Lock()
+		job, exists := q.queued[key]
+		if !exists {
+			delete(q.running, key)
+			q.Unlock()
+			return
+		}
+		delete(q.queued, key)
+		started := time.Now().UTC()
+		job.Status = JobRunning
+		job.StartedAt = &started
+		q.Unlock()
+
+		app.runRefreshJob(job)
+
+		q.Lock()
+		finished := time.Now().UTC()
+		job.FinishedAt = &finished
+		q.finished = append(q.finished, job.ID)
+		if len(q.finished) > maxFinishedJobs {
+			delete(q.jobs, q.finished[0])
+			q.finished = q.finished[1:]
+		}
+		q.Unlock()
+	}
+}
+
+// update applies fn while holding the queue lock, so status requests see consistent job progress.
+func (q *jobQueue) update(fn func()) {
+	q.Lock()
+	defer q.Unlock()
+
+	fn()
+}
+
+// view returns a copy of the job that is safe to use without the queue lock. The caller must hold the lock.
+func (job *RefreshJob) view() RefreshJob {
+	view := *job
+	view.Collections = slices.Clone(job.Collections)
+	view.Reports = slices.Clone(job.Reports)
+	return view
+}
+
+// RefreshJobStatus reports the status, progress and per-file results of a refresh job queued by a webhook.
+func (app *Application) RefreshJobStatus(c echo.Context) error {
+	app.jobs.Lock()
+	job, exists := app.jobs.jobs[c.Param("id")]
+	var view RefreshJob
+	if exists {
+		view = job.view()
+	}
+	app.jobs.Unlock()
+
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "job not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, view)
+}
+
+// newJobID returns a random, unguessable job ID, so job status URLs can be shared without authentication.
+func newJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// liveReloadKeepAlive is how often a comment is sent on idle live reload streams to keep proxies from closing them.
+const liveReloadKeepAlive = 30 * time.Second
+
+// startWatching watches the local directory of every collection and notifies open browser tabs when a file changes.
+// Collections that are not backed by a local directory are skipped with a warning.
+func (app *Application) startWatching() {
+	for _, collection := range app.ContentManager.All() {
+		app.background.Add(1)
+		go func() {
+			defer app.background.Done()
+			err := collection.Watch(app.ctx, func(path string) {
+				app.reloads.Notify()
+			})
+			if err != nil {
+				log.Printf("WARNING: not watching %s: %v", collection.Name(), err)
+			}
+		}()
+	}
+}
+
+// LiveReload streams server-sent events to the browser, emitting a "reload" event whenever watched content changes.
+func (app *Application) LiveReload(c echo.Context) error {
+	if app.reloads == nil {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	events, unsubscribe := app.reloads.Subscribe()
+	defer unsubscribe()
+
+	ticker := time.NewTicker(liveReloadKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-app.ctx.Done():
+			return nil
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			w.Flush()
+		case <-events:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			w.Flush()
+		}
+	}
+}
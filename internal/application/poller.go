@@ -0,0 +1,65 @@
+package application
+
+import (
+	"log"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+)
+
+// startPolling checks the source of every collection with a poll interval for changes in the background and
+// refreshes the collection when it has changed, so the site catches up if a webhook delivery is lost.
+// Local sources are skipped, since they are watched in watch mode instead, and so is content served from an
+// embedded bundle, which only changes through webhook refreshes.
+func (app *Application) startPolling() {
+	if app.bundle != "" {
+		log.Printf("Serving embedded content bundle %s, not polling content sources", app.bundle)
+		return
+	}
+
+	for _, collection := range app.ContentManager.All() {
+		config := collection.Config()
+		if config.PollInterval <= 0 || config.Source.Type == contentmanager.SourceLocal {
+			continue
+		}
+
+		log.Printf("Polling %s source %s for changes every %v", collection.Name(), collection.Source(), config.PollInterval)
+
+		app.background.Add(1)
+		go func() {
+			defer app.background.Done()
+			app.poll(collection, config.PollInterval)
+		}()
+	}
+}
+
+// poll checks the collection's source for changes every interval until the application is closed.
+func (app *Application) poll(collection *contentmanager.Collection, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := collection.Poll(app.ctx)
+		if err != nil {
+			log.Printf("Failed to poll %s: %v", collection.Name(), err)
+			continue
+		}
+
+		// The source has not changed since the last complete refresh
+		if report == nil {
+			continue
+		}
+
+		log.Printf("Poll refreshed %s: %d files updated, %d removed, %d failed", collection.Name(),
+			report.Count(contentmanager.FileUpdated), report.Count(contentmanager.FileRemoved), report.Count(contentmanager.FileFailed))
+
+		// Persist whatever was refreshed so a restart serves it immediately
+		app.saveSnapshot()
+	}
+}
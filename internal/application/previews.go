@@ -0,0 +1,539 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/labstack/echo/v4"
+)
+
+// previewCookie holds the access token of a preview once its link has been opened.
+const previewCookie = "preview_token"
+
+// previewKey is the echo.Context key of the preview a request is for.
+const previewKey = "preview"
+
+// PullRequestPayload represents a GitHub pull_request event.
+type PullRequestPayload struct {
+	Action      string `json:"action"` // e.g. "opened", "synchronize", "reopened" or "closed"
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title             string `json:"title"`
+		AuthorAssociation string `json:"author_association"` // e.g. "MEMBER" or "CONTRIBUTOR"
+		Head              struct {
+			Ref  string            `json:"ref"`
+			SHA  string            `json:"sha"`
+			Repo WebhookRepository `json:"repo"` // Repository the pull request's branch is in, a fork's for pull requests from forks
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository WebhookRepository `json:"repository"` // Repository the pull request is against
+}
+
+// fromFork reports whether the pull request's branch is in another repository than the one it is against.
+// The head repository is missing if the fork has been deleted.
+func (payload PullRequestPayload) fromFork() bool {
+	head := payload.PullRequest.Head.Repo.FullName
+	return head == "" || !strings.EqualFold(head, payload.Repository.FullName)
+}
+
+// Preview is the content of a pull request, loaded into its own collections next to the live content and served
+// under /preview/<owner>/<repo>/<number>/, see PreviewAccess.
+type Preview struct {
+	Number     int       `json:"number"`
+	Repository string    `json:"repository"`
+	Title      string    `json:"title"`
+	Branch     string    `json:"branch"`
+	SHA        string    `json:"sha"`    // Head commit of the pull request the preview is built from
+	Status     string    `json:"status"` // building, ready or failed
+	Error      string    `json:"error,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	URL        string    `json:"url,omitempty"` // Signed link to the preview, only included for operators
+
+	loaded  bool                           // Whether the preview has been built at least once
+	content *contentmanager.ContentManager // The preview's copies of the repository's collections
+	ctx     context.Context                // Cancelled when the preview is torn down
+	cancel  context.CancelFunc
+}
+
+// id returns the key the preview is held under.
+func (preview *Preview) id() previewID {
+	return newPreviewID(preview.Repository, preview.Number)
+}
+
+// previewID identifies the preview of a pull request. Pull request numbers are only unique within a repository, so
+// pull requests of different content repositories with the same number each have their own preview.
+type previewID struct {
+	Repo   string // Full name of the repository, owner/name in lower case
+	Number int
+}
+
+// newPreviewID returns the ID of the preview of the given pull request of the repository with the given full name.
+func newPreviewID(repo string, number int) previewID {
+	return previewID{Repo: strings.ToLower(repo), Number: number}
+}
+
+// String returns the ID as used in access tokens, e.g. "jgndev/posts#12".
+func (id previewID) String() string {
+	return id.Repo + "#" + strconv.Itoa(id.Number)
+}
+
+// path returns the path the preview is served under, without a trailing slash.
+func (id previewID) path() string {
+	return "/preview/" + id.Repo + "/" + strconv.Itoa(id.Number)
+}
+
+// previewSet holds the previews being served, keyed by repository and pull request number, and signs their access
+// tokens.
+type previewSet struct {
+	sync.Mutex
+	previews map[previewID]*Preview
+	key      []byte
+	ttl      time.Duration
+	max      int
+}
+
+// newPreviewSet initializes and returns a pointer to an empty previewSet holding at most max previews, whose links
+// are signed with secret and stay valid for ttl. Without a secret a random key is used, so links stop working when
+// the process restarts, as do the previews themselves.
+func newPreviewSet(secret string, ttl time.Duration, max int) *previewSet {
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+
+	return &previewSet{
+		previews: make(map[previewID]*Preview),
+		key:      key,
+		ttl:      ttl,
+		max:      max,
+	}
+}
+
+// get returns the preview with the given ID, or nil if there is none.
+func (s *previewSet) get(id previewID) *Preview {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.previews[id]
+}
+
+// token returns an access token to the preview with the given ID, valid until expires.
+// Tokens have the form base64url("<owner>/<repo>#<number>.<expires unix>").<hex HMAC-SHA256>.
+func (s *previewSet) token(id previewID, expires time.Time) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%s.%d", id, expires.Unix())))
+	return claims + "." + s.sign(claims)
+}
+
+// verify reports whether the token grants access to the preview with the given ID and hasn't expired,
+// and when it expires.
+func (s *previewSet) verify(id previewID, token string) (time.Time, bool) {
+	claims, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(claims))) {
+		return time.Time{}, false
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(claims)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	// Repository names may contain dots, the expiry doesn't
+	i := strings.LastIndex(string(decoded), ".")
+	if i < 0 || string(decoded[:i]) != id.String() {
+		return time.Time{}, false
+	}
+	expiresUnix := string(decoded[i+1:])
+
+	seconds, err := strconv.ParseInt(expiresUnix, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	expires := time.Unix(seconds, 0)
+	return expires, time.Now().Before(expires)
+}
+
+// sign returns the hex HMAC-SHA256 of the token claims.
+func (s *previewSet) sign(claims string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("preview:" + claims))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// previewURL returns a link to the preview with the given ID carrying a fresh access token.
+func (app *Application) previewURL(id previewID) string {
+	token := app.previews.token(id, time.Now().Add(app.previews.ttl))
+	return strings.TrimSuffix(app.site.URL, "/") + id.path() + "/?token=" + token
+}
+
+// handlePullRequest builds a preview of the content of a pull request against a content repository when it is
+// opened, reopened or pushed to, and tears the preview down when the pull request is closed or merged.
+// Other actions are acknowledged and ignored.
+func (app *Application) handlePullRequest(header http.Header, body []byte) webhookResponse {
+	if !app.Config.Features.Previews {
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "pull request previews are disabled",
+		}}
+	}
+
+	var payload PullRequestPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse pull request payload: %v", err)
+		return webhookResponse{http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		}}
+	}
+
+	switch payload.Action {
+	case "opened", "reopened", "synchronize":
+		if payload.fromFork() && !app.previewsFork(payload.PullRequest.AuthorAssociation) {
+			log.Printf("Not previewing pull request #%d of %s from a fork by a %s author", payload.Number, payload.Repository.FullName, payload.PullRequest.AuthorAssociation)
+			return webhookResponse{http.StatusOK, map[string]string{
+				"message": "pull requests from forks by " + payload.PullRequest.AuthorAssociation + " authors are not previewed",
+			}}
+		}
+		return app.deployPreview(payload)
+	case "closed":
+		return app.removePreview(payload)
+	default:
+		log.Printf("Ignoring %s action on pull request #%d of %s", payload.Action, payload.Number, payload.Repository.FullName)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "pull request action ignored",
+		}}
+	}
+}
+
+// previewsFork reports whether pull requests from forks by authors with the given association to the repository
+// are previewed. Building a preview fetches and serves whatever the fork contains, so only authors trusted with
+// the repository's content are, as set by previews.fork_authors.
+func (app *Application) previewsFork(association string) bool {
+	return slices.ContainsFunc(app.Config.Previews.ForkAuthors, func(allowed string) bool {
+		return strings.EqualFold(allowed, association)
+	})
+}
+
+// deployPreview creates the preview of a pull request, or moves an existing one to the pull request's new head,
+// and builds it in the background. The signed link to the preview is posted as the status of the head commit
+// rather than included in the response, which is kept in the delivery history.
+func (app *Application) deployPreview(payload PullRequestPayload) webhookResponse {
+	repo := app.ContentManager.ResolveRepo(payload.Repository.Path())
+	collections := app.ContentManager.ForRepo(repo)
+	if len(collections) == 0 {
+		log.Printf("No collections read from %s, not previewing pull request #%d", repo, payload.Number)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "no collections read from this repository",
+		}}
+	}
+
+	id := newPreviewID(repo, payload.Number)
+	app.previews.Lock()
+	preview, exists := app.previews.previews[id]
+	switch {
+	case !exists && len(app.previews.previews) >= app.previews.max:
+		app.previews.Unlock()
+		log.Printf("Not previewing pull request #%d of %s, %d previews are already served", payload.Number, repo, app.previews.max)
+
+		// Tell the pull request why it has no preview, rather than leaving it without a status
+		app.background.Add(1)
+		go func() {
+			defer app.background.Done()
+			status := contentmanager.CommitStatus{State: "error", Description: "Preview limit reached, close another pull request first"}
+			if err := app.setCommitStatus(app.ctx, collections, payload.PullRequest.Head.SHA, status); err != nil {
+				log.Printf("Failed to set the preview status of pull request #%d of %s: %v", payload.Number, repo, err)
+			}
+		}()
+
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "preview limit reached, close another pull request first",
+		}}
+	case !exists:
+		var err error
+		if preview, err = app.newPreview(payload.Number, repo, collections); err != nil {
+			app.previews.Unlock()
+			log.Printf("Failed to set up the preview of pull request #%d of %s: %v", payload.Number, repo, err)
+			return webhookResponse{http.StatusInternalServerError, map[string]string{
+				"error": "failed to set up preview",
+			}}
+		}
+		app.previews.previews[id] = preview
+	}
+
+	preview.Title = payload.PullRequest.Title
+	preview.Branch = payload.PullRequest.Head.Ref
+	preview.SHA = payload.PullRequest.Head.SHA
+	preview.Status = "building"
+	preview.Error = ""
+	preview.UpdatedAt = time.Now().UTC()
+	app.previews.Unlock()
+
+	log.Printf("Building the preview of pull request #%d of %s at %s", payload.Number, repo, payload.PullRequest.Head.SHA)
+
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		app.buildPreview(preview)
+	}()
+
+	return webhookResponse{http.StatusAccepted, map[string]string{
+		"message": "preview deployment started",
+	}}
+}
+
+// newPreview returns an unbuilt preview with a copy of each of the given collections. The copies are read at a ref
+// set when the preview is built and are never polled; webhooks for the pull request update them instead.
+func (app *Application) newPreview(number int, repo string, collections []*contentmanager.Collection) (*Preview, error) {
+	content := contentmanager.NewContentManager(app.ContentManager.Clients())
+	for _, collection := range collections {
+		config := collection.Config()
+		config.PollInterval = 0
+		config.FollowReleases = false
+		config.Source.Ref = ""
+
+		if _, err := content.Add(config); err != nil {
+			return nil, fmt.Errorf("failed to copy the %s collection: %w", config.Name, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(app.ctx)
+
+	return &Preview{
+		Number:     number,
+		Repository: repo,
+		content:    content,
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// buildPreview pins the preview's collections to the pull request's head and reports the outcome as the status of
+// the head commit. Builds of a preview run one after another; each reads the head when it starts, so a build
+// started for an older push moves to the latest head instead.
+func (app *Application) buildPreview(preview *Preview) {
+	app.previews.Lock()
+	sha := preview.SHA
+	app.previews.Unlock()
+
+	app.setPreviewStatus(preview, sha, contentmanager.CommitStatus{State: "pending", Description: "Building the preview"})
+
+	var failures []string
+	for _, collection := range preview.content.All() {
+		report, err := collection.PinRef(preview.ctx, sha)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %v", collection.Name(), err))
+		case len(report.Failed()) > 0:
+			failures = append(failures, fmt.Sprintf("%s: %d files failed to load", collection.Name(), len(report.Failed())))
+		}
+	}
+
+	// The preview was removed while it was built; drop whatever the build cached after the removal
+	if preview.ctx.Err() != nil {
+		preview.discard()
+		return
+	}
+
+	app.previews.Lock()
+
+	// A newer push has its own build, which reports the outcome
+	if preview.SHA != sha {
+		app.previews.Unlock()
+		return
+	}
+
+	preview.loaded = true
+	preview.UpdatedAt = time.Now().UTC()
+	status := contentmanager.CommitStatus{State: "success", TargetURL: app.previewURL(preview.id()), Description: "Preview ready"}
+	if len(failures) > 0 {
+		preview.Status = "failed"
+		preview.Error = strings.Join(failures, "; ")
+		log.Printf("Preview of pull request #%d of %s failed at %s: %s", preview.Number, preview.Repository, sha, preview.Error)
+		status = contentmanager.CommitStatus{State: "failure", TargetURL: status.TargetURL, Description: preview.Error}
+	} else {
+		preview.Status = "ready"
+		log.Printf("Preview of pull request #%d of %s is ready at %s", preview.Number, preview.Repository, sha)
+	}
+	app.previews.Unlock()
+
+	app.setPreviewStatus(preview, sha, status)
+}
+
+// setPreviewStatus sets the status of a commit of the preview's pull request through the source of one of the
+// preview's collections. Failures are only logged, as the preview is served either way.
+func (app *Application) setPreviewStatus(preview *Preview, sha string, status contentmanager.CommitStatus) {
+	if err := app.setCommitStatus(preview.ctx, preview.content.All(), sha, status); err != nil && preview.ctx.Err() == nil {
+		log.Printf("Failed to set the preview status of pull request #%d of %s: %v", preview.Number, preview.Repository, err)
+	}
+}
+
+// setCommitStatus sets the status of a commit through the source of the first of the given collections that can,
+// under the site's "<site>/preview" context. The token or GitHub App the collection reads with needs permission to
+// write commit statuses.
+func (app *Application) setCommitStatus(ctx context.Context, collections []*contentmanager.Collection, sha string, status contentmanager.CommitStatus) error {
+	status.Context = app.Config.Site.Name + "/preview"
+
+	for _, collection := range collections {
+		if source, ok := collection.Source().(contentmanager.StatusSource); ok {
+			return source.SetStatus(ctx, sha, status)
+		}
+	}
+
+	return nil
+}
+
+// discard drops the API responses cached for the preview's collections, which are not read again.
+func (preview *Preview) discard() {
+	for _, collection := range preview.content.All() {
+		collection.Discard()
+	}
+}
+
+// removePreview tears down the preview of a closed pull request, stopping any build in progress.
+func (app *Application) removePreview(payload PullRequestPayload) webhookResponse {
+	id := newPreviewID(app.ContentManager.ResolveRepo(payload.Repository.Path()), payload.Number)
+
+	app.previews.Lock()
+	preview, exists := app.previews.previews[id]
+	delete(app.previews.previews, id)
+	app.previews.Unlock()
+
+	if !exists {
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "no preview to remove",
+		}}
+	}
+
+	preview.cancel()
+	preview.discard()
+	log.Printf("Removed the preview of pull request #%d of %s", payload.Number, payload.Repository.FullName)
+
+	return webhookResponse{http.StatusOK, map[string]string{
+		"message": "preview removed",
+	}}
+}
+
+// PreviewAccess is middleware serving the routes of a group below /preview/<owner>/<repo>/<number> from the preview
+// of that pull request. Access requires the token from the preview's link: a ?token= parameter is exchanged for a cookie scoped
+// to the preview and the link is redirected without it, so the token doesn't end up in referrers or history.
+// Pages link below the preview and read the preview's copies of its repository's collections, and the live site's
+// other collections. Preview pages are never indexed or cached.
+func (app *Application) PreviewAccess(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Response().Header()
+		header.Set("X-Robots-Tag", "noindex, nofollow")
+		header.Set("Cache-Control", "private, no-store")
+
+		number, err := strconv.Atoi(c.Param("number"))
+		if err != nil {
+			return c.String(http.StatusNotFound, "Preview not found")
+		}
+
+		id := newPreviewID(c.Param("owner")+"/"+c.Param("repo"), number)
+		preview := app.previews.get(id)
+		if preview == nil {
+			return c.String(http.StatusNotFound, "Preview not found")
+		}
+
+		prefix := id.path()
+		if token := c.QueryParam("token"); token != "" {
+			expires, ok := app.previews.verify(id, token)
+			if !ok {
+				return c.String(http.StatusUnauthorized, "Invalid or expired preview link")
+			}
+
+			c.SetCookie(&http.Cookie{
+				Name:     previewCookie,
+				Value:    token,
+				Path:     prefix,
+				Expires:  expires,
+				HttpOnly: true,
+				Secure:   c.Scheme() == "https",
+				SameSite: http.SameSiteLaxMode,
+			})
+
+			query := c.Request().URL.Query()
+			query.Del("token")
+			target := c.Request().URL.Path
+			if encoded := query.Encode(); encoded != "" {
+				target += "?" + encoded
+			}
+			return c.Redirect(http.StatusSeeOther, target)
+		}
+
+		cookie, err := c.Cookie(previewCookie)
+		if err != nil {
+			return c.String(http.StatusUnauthorized, "Open the preview from its link")
+		}
+		if _, ok := app.previews.verify(id, cookie.Value); !ok {
+			return c.String(http.StatusUnauthorized, "Invalid or expired preview link")
+		}
+
+		app.previews.Lock()
+		loaded, status := preview.loaded, preview.Status
+		app.previews.Unlock()
+
+		if !loaded {
+			header.Set("Retry-After", "10")
+			return c.String(http.StatusServiceUnavailable, "The preview is being built, try again shortly")
+		}
+
+		c.Set(previewKey, preview)
+		header.Set("X-Preview-Status", status)
+
+		var revisions []string
+		for _, live := range app.ContentManager.All() {
+			collection, _ := app.collectionFor(c, live.Name())
+			if revision := collection.ServedRevision(); revision != "" {
+				revisions = append(revisions, collection.Name()+"="+revision)
+			}
+		}
+		header.Del("X-Content-Revision")
+		if len(revisions) > 0 {
+			header.Set("X-Content-Revision", strings.Join(revisions, ", "))
+		}
+
+		metadata := site.FromContext(c.Request().Context())
+		metadata.BasePath = prefix
+		metadata.NoIndex = true
+		c.SetRequest(c.Request().WithContext(site.WithMetadata(c.Request().Context(), metadata)))
+
+		return next(c)
+	}
+}
+
+// Previews lists the pull request previews being served, with a signed link to each, ordered by repository and number.
+func (app *Application) Previews(c echo.Context) error {
+	app.previews.Lock()
+	previews := make([]Preview, 0, len(app.previews.previews))
+	for _, preview := range app.previews.previews {
+		view := *preview
+		view.URL = app.previewURL(preview.id())
+		previews = append(previews, view)
+	}
+	app.previews.Unlock()
+
+	sort.Slice(previews, func(i, j int) bool {
+		if previews[i].Repository != previews[j].Repository {
+			return previews[i].Repository < previews[j].Repository
+		}
+		return previews[i].Number < previews[j].Number
+	})
+
+	return c.JSON(http.StatusOK, previews)
+}
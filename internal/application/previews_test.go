@@ -0,0 +1,188 @@
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/labstack/echo/v4"
+)
+
+// localPosts returns a posts collection read from a directory holding a published post with the given slug.
+func localPosts(t *testing.T, slug string) *contentmanager.ContentManager {
+	t.Helper()
+
+	dir := t.TempDir()
+	post := "---\ntitle: " + slug + "\nslug: " + slug + "\npublished: true\n---\nHello"
+	if err := os.WriteFile(filepath.Join(dir, slug+".md"), []byte(post), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	content := contentmanager.NewContentManager(nil)
+	collection, err := content.Add(contentmanager.CollectionConfig{
+		Name:        "posts",
+		RoutePrefix: "/posts",
+		Source:      contentmanager.SourceConfig{Type: contentmanager.SourceLocal, Dir: dir},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collection.RefreshContent(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return content
+}
+
+// previewServer returns an application serving the live post "live", a ready preview of pull request #12 of
+// jgndev/posts with the post "draft" instead and one of pull request #12 of jgndev/notes with the post "note",
+// and a server routing requests to it as the server command does.
+func previewServer(t *testing.T) (*Application, *echo.Echo) {
+	t.Helper()
+
+	cfg := &config.Config{}
+	cfg.Features.Previews = true
+	cfg.Previews.ForkAuthors = []string{"OWNER", "MEMBER", "COLLABORATOR"}
+
+	app := &Application{
+		Config:         cfg,
+		ContentManager: localPosts(t, "live"),
+		site:           site.Default,
+		views:          map[string]CollectionViews{"posts": viewSets["posts"]},
+		previews:       newPreviewSet("secret", time.Hour, 10),
+		ctx:            context.Background(),
+	}
+	for repo, slug := range map[string]string{"jgndev/posts": "draft", "jgndev/notes": "note"} {
+		preview := &Preview{
+			Number:     12,
+			Repository: repo,
+			Status:     "ready",
+			loaded:     true,
+			content:    localPosts(t, slug),
+		}
+		preview.ctx, preview.cancel = context.WithCancel(context.Background())
+		t.Cleanup(preview.cancel)
+		app.previews.previews[preview.id()] = preview
+	}
+
+	e := echo.New()
+	e.Use(app.SiteContext)
+	app.RegisterCollectionRoutes(e)
+	preview := e.Group("/preview/:owner/:repo/:number", app.PreviewAccess)
+	preview.GET("/", app.Home)
+	app.RegisterCollectionRoutes(preview)
+
+	return app, e
+}
+
+func get(e *echo.Echo, target string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestPreviewAccess(t *testing.T) {
+	app, e := previewServer(t)
+
+	if rec := get(e, "/preview/jgndev/posts/12/posts/draft"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("preview without a token answered with %d, want 401", rec.Code)
+	}
+	if rec := get(e, "/preview/jgndev/posts/12/posts/draft?token=forged"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("preview with a forged token answered with %d, want 401", rec.Code)
+	}
+
+	token := app.previews.token(newPreviewID("jgndev/posts", 12), time.Now().Add(time.Hour))
+	rec := get(e, "/preview/jgndev/posts/12/posts/draft?token="+token)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/preview/jgndev/posts/12/posts/draft" {
+		t.Fatalf("preview link answered with %d to %q, want a redirect without the token", rec.Code, rec.Header().Get("Location"))
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Path != "/preview/jgndev/posts/12" {
+		t.Fatalf("cookies = %v, want the token scoped to the preview", cookies)
+	}
+
+	rec = get(e, "/preview/jgndev/posts/12/posts/draft", cookies...)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("preview page answered with %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{`href="/preview/jgndev/posts/12/posts"`, `content="noindex, nofollow"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview page doesn't contain %s", want)
+		}
+	}
+	if rec.Header().Get("X-Preview-Status") != "ready" || rec.Header().Get("Cache-Control") != "private, no-store" {
+		t.Errorf("preview headers = %v, want the preview's status and no caching", rec.Header())
+	}
+
+	// The preview's copy of the collection replaces the live one, which is unchanged
+	if rec := get(e, "/preview/jgndev/posts/12/posts/live", cookies...); rec.Code != http.StatusNotFound {
+		t.Errorf("live post in the preview answered with %d, want 404", rec.Code)
+	}
+	if rec := get(e, "/posts/draft"); rec.Code != http.StatusNotFound {
+		t.Errorf("preview post on the live site answered with %d, want 404", rec.Code)
+	}
+	rec = get(e, "/posts/live")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="/posts"`) || strings.Contains(rec.Body.String(), "noindex") {
+		t.Errorf("live post answered with %d, want it linking to the live site and indexable", rec.Code)
+	}
+}
+
+func TestPreviewForkAuthors(t *testing.T) {
+	app, _ := previewServer(t)
+
+	fork := `{"action": "opened", "number": 13, "pull_request": {"author_association": "CONTRIBUTOR",
+		"head": {"ref": "main", "sha": "abc", "repo": {"name": "posts", "full_name": "someone/posts"}}},
+		"repository": {"name": "posts", "full_name": "jgndev/posts"}}`
+	resp := app.handlePullRequest(nil, []byte(fork))
+	if message := resp.body.(map[string]string)["message"]; !strings.Contains(message, "not previewed") {
+		t.Errorf("pull request from a fork by a contributor answered with %q, want it not previewed", message)
+	}
+	if app.previews.get(newPreviewID("jgndev/posts", 13)) != nil {
+		t.Error("pull request from a fork by a contributor was previewed")
+	}
+}
+
+func TestPreviewsOfPullRequestsWithTheSameNumber(t *testing.T) {
+	app, e := previewServer(t)
+
+	posts := app.previews.token(newPreviewID("jgndev/posts", 12), time.Now().Add(time.Hour))
+	notes := app.previews.token(newPreviewID("jgndev/notes", 12), time.Now().Add(time.Hour))
+
+	// A link to one repository's pull request doesn't open another's with the same number
+	if rec := get(e, "/preview/jgndev/notes/12/posts/note?token="+posts); rec.Code != http.StatusUnauthorized {
+		t.Errorf("preview with another repository's token answered with %d, want 401", rec.Code)
+	}
+
+	rec := get(e, "/preview/jgndev/notes/12/posts/note?token="+notes)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("preview link answered with %d, want a redirect", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if rec := get(e, "/preview/jgndev/notes/12/posts/note", cookies...); rec.Code != http.StatusOK {
+		t.Errorf("preview of jgndev/notes answered with %d, want 200", rec.Code)
+	}
+	if rec := get(e, "/preview/jgndev/notes/12/posts/draft", cookies...); rec.Code != http.StatusNotFound {
+		t.Errorf("jgndev/posts post in the preview of jgndev/notes answered with %d, want 404", rec.Code)
+	}
+
+	// Closing one pull request leaves the other's preview
+	closed := `{"action": "closed", "number": 12, "repository": {"name": "notes", "full_name": "jgndev/notes"}}`
+	app.handlePullRequest(nil, []byte(closed))
+	if app.previews.get(newPreviewID("jgndev/notes", 12)) != nil || app.previews.get(newPreviewID("jgndev/posts", 12)) == nil {
+		t.Error("closing pull request #12 of jgndev/notes didn't remove only its preview")
+	}
+}
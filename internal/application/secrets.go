@@ -0,0 +1,199 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+)
+
+// webhookKey is a secret webhook deliveries can be signed with.
+type webhookKey struct {
+	name     string // Where the secret is configured, logged when it matches
+	secret   string
+	previous bool // Whether the secret is being retired
+}
+
+// webhookKeys returns the secrets accepted for webhooks from the repository with the given full name, e.g.
+// "jgndev/posts", given the provider's secret environment variable, e.g. GITHUB_WEBHOOK_SECRET. Renamed repositories
+// use the secrets of the name their collections are configured with, as their events are routed to those.
+// The first of these that is configured is used:
+//   - the repository's entry in webhook.secrets
+//   - the <env>_<OWNER>_<REPO> and <env>_<OWNER>_<REPO>_PREVIOUS environment variables, e.g.
+//     GITHUB_WEBHOOK_SECRET_JGNDEV_POSTS
+//   - the site's webhook_secret
+//   - the webhook.secrets entry without a repository
+//   - the <env> and <env>_PREVIOUS environment variables
+//
+// Repositories with their own secrets therefore don't accept deliveries signed with another repository's secret.
+// webhook.secrets is shared by every site, so a site's own secret wins over its default entry.
+func (app *Application) webhookKeys(env, repo string) []webhookKey {
+	repo = app.ContentManager.ResolveRepo(repo)
+
+	var defaults *config.WebhookSecretConfig
+	for i, secrets := range app.Config.Webhook.Secrets {
+		switch {
+		case secrets.Repo == "":
+			defaults = &app.Config.Webhook.Secrets[i]
+		case repo != "" && strings.EqualFold(secrets.Repo, repo):
+			return configuredKeys(secrets)
+		}
+	}
+
+	if repo != "" {
+		if keys := envKeys(env + "_" + envName(repo)); len(keys) > 0 {
+			return keys
+		}
+	}
+
+	if app.Config.Site.WebhookSecret != "" {
+		return []webhookKey{{name: "site webhook_secret", secret: app.Config.Site.WebhookSecret}}
+	}
+
+	if defaults != nil {
+		return configuredKeys(*defaults)
+	}
+
+	return envKeys(env)
+}
+
+// configuredKeys returns the current and previous secret of a webhook.secrets entry, reading them from its file
+// if it has one. The file is read on every delivery, so a rotated secrets mount takes effect without a restart.
+func configuredKeys(secrets config.WebhookSecretConfig) []webhookKey {
+	name := "webhook.secrets default"
+	if secrets.Repo != "" {
+		name = "webhook.secrets " + secrets.Repo
+	}
+
+	current, previous := secrets.Current, secrets.Previous
+	if secrets.File != "" {
+		var err error
+		current, previous, err = readSecretsFile(secrets.File)
+		if err != nil {
+			log.Printf("Failed to read webhook secrets of %s: %v", name, err)
+			return nil
+		}
+		name += " (" + secrets.File + ")"
+	}
+
+	var keys []webhookKey
+	if current != "" {
+		keys = append(keys, webhookKey{name: name + " current", secret: current})
+	}
+	if previous != "" {
+		keys = append(keys, webhookKey{name: name + " previous", secret: previous, previous: true})
+	}
+
+	return keys
+}
+
+// readSecretsFile reads the current secret from the first non-empty line of a secrets file and the previous
+// secret, if any, from the second.
+func readSecretsFile(path string) (current, previous string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	var secrets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			secrets = append(secrets, line)
+		}
+	}
+
+	switch len(secrets) {
+	case 0:
+		return "", "", fmt.Errorf("%s is empty", path)
+	case 1:
+		return secrets[0], "", nil
+	default:
+		return secrets[0], secrets[1], nil
+	}
+}
+
+// envKeys returns the secrets in the named environment variable and its _PREVIOUS counterpart.
+func envKeys(env string) []webhookKey {
+	var keys []webhookKey
+	if secret := os.Getenv(env); secret != "" {
+		keys = append(keys, webhookKey{name: env, secret: secret})
+	}
+	if secret := os.Getenv(env + "_PREVIOUS"); secret != "" {
+		keys = append(keys, webhookKey{name: env + "_PREVIOUS", secret: secret, previous: true})
+	}
+
+	return keys
+}
+
+// envName returns the repository's full name as used in environment variable names, e.g. "JGNDEV_POSTS" for
+// "jgndev/posts" or "JGNDEV_MY_NOTES" for "jgndev/my-notes".
+func envName(repo string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, repo)
+}
+
+// webhookRepo returns the full name of the repository a GitHub or Gitea webhook payload is about, as its event is
+// routed by (see WebhookRepository.Path), so the repository's secrets can be looked up before the payload is
+// verified. Payloads whose repository name contradicts the full name are rejected, since the secrets of one
+// repository must not verify events routed to another.
+func webhookRepo(body []byte) (string, error) {
+	var payload struct {
+		Repository WebhookRepository `json:"repository"`
+	}
+	json.Unmarshal(body, &payload)
+
+	repo := payload.Repository
+	if repo.Name != "" && repo.FullName != "" && !strings.EqualFold(path.Base(repo.FullName), repo.Name) {
+		return "", fmt.Errorf("repository name %q doesn't match its full name %q", repo.Name, repo.FullName)
+	}
+
+	return repo.Path(), nil
+}
+
+// gitlabProjectPath returns the path of the project a GitLab webhook payload is about, e.g. "group/project", as its
+// event is routed by, so the project's secrets can be looked up before the payload is verified.
+func gitlabProjectPath(body []byte) string {
+	var payload GitLabWebhookPayload
+	json.Unmarshal(body, &payload)
+
+	if payload.Project.PathWithNamespace != "" {
+		return payload.Project.PathWithNamespace
+	}
+	return payload.Project.Name
+}
+
+// matchWebhookKey returns the key valid accepts. Every key is checked, each in constant time, so the response time
+// doesn't reveal how many keys there are or which one matched. The match is logged, along with a warning when a
+// previous secret is still in use.
+func matchWebhookKey(provider, repo string, keys []webhookKey, valid func(secret string) bool) (webhookKey, bool) {
+	var matched webhookKey
+	found := false
+	for _, key := range keys {
+		if valid(key.secret) && !found {
+			matched, found = key, true
+		}
+	}
+
+	if !found {
+		return webhookKey{}, false
+	}
+
+	log.Printf("%s webhook from %s verified with %s", provider, repo, matched.name)
+	if matched.previous {
+		log.Printf("WARNING: %s is still signing webhooks with the previous secret %s, keep it until the sender uses the current one", repo, matched.name)
+	}
+
+	return matched, true
+}
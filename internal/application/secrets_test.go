@@ -0,0 +1,86 @@
+package application
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/labstack/echo/v4"
+)
+
+func TestWebhookKeysPrecedence(t *testing.T) {
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "env")
+
+	cfg := &config.Config{}
+	cfg.Site.WebhookSecret = "site"
+	cfg.Webhook.Secrets = []config.WebhookSecretConfig{
+		{Current: "default"},
+		{Repo: "jgndev/posts", Current: "posts"},
+	}
+	app := &Application{Config: cfg, ContentManager: contentmanager.NewContentManager(nil)}
+
+	tests := []struct {
+		name string
+		repo string
+		want string
+	}{
+		{"repository entry", "jgndev/posts", "posts"},
+		{"same name of another owner", "someone/posts", "site"},
+		{"site secret before repo-less entry", "jgndev/notes", "site"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := app.webhookKeys("GITHUB_WEBHOOK_SECRET", tt.repo)
+			if len(keys) != 1 || keys[0].secret != tt.want {
+				t.Fatalf("webhookKeys(%q) = %+v, want %q", tt.repo, keys, tt.want)
+			}
+		})
+	}
+
+	cfg.Site.WebhookSecret = ""
+	if keys := app.webhookKeys("GITHUB_WEBHOOK_SECRET", "jgndev/notes"); len(keys) != 1 || keys[0].secret != "default" {
+		t.Fatalf("without a site secret webhookKeys = %+v, want the repo-less entry", keys)
+	}
+}
+
+func TestWebhookSecretOfAnotherRepository(t *testing.T) {
+	app := webhookApp()
+	app.Config.Webhook.Secrets = []config.WebhookSecretConfig{
+		{Repo: "jgndev/posts", Current: "posts-secret"},
+		{Repo: "jgndev/cheatsheets", Current: "cheatsheets-secret"},
+	}
+
+	// Payloads signed with the cheatsheets secret, claiming to be about posts
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"mismatched name", `{"repository": {"name": "cheatsheets", "full_name": "jgndev/posts"}}`, http.StatusBadRequest},
+		{"posts", `{"repository": {"name": "posts", "full_name": "jgndev/posts"}}`, http.StatusUnauthorized},
+		{"posts without a name", `{"repository": {"full_name": "jgndev/posts"}}`, http.StatusUnauthorized},
+		{"cheatsheets of another owner", `{"repository": {"name": "cheatsheets", "full_name": "someone/cheatsheets"}}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mac := hmac.New(sha256.New, []byte("cheatsheets-secret"))
+			mac.Write([]byte(tt.body))
+
+			req := httptest.NewRequest(http.MethodPost, "/webhook/github", strings.NewReader(tt.body))
+			req.Header.Set("X-GitHub-Event", "push")
+			req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+			rec := httptest.NewRecorder()
+			app.WebhookHandler(echo.New().NewContext(req, rec))
+
+			if rec.Code != tt.want {
+				t.Errorf("delivery answered with %d %s, want %d", rec.Code, rec.Body, tt.want)
+			}
+		})
+	}
+}
@@ -55,9 +55,10 @@ package application
 
 import (
 	"encoding/xml"
+	"net/http"
+	"strings"
 	"time"
 
-	"github.com/jgndev/jgn.dev/internal/site"
 	"github.com/labstack/echo/v4"
 )
 
@@ -77,10 +78,25 @@ type URLSet struct {
 	URLs    []URLEntry `xml:"url"`
 }
 
-// SitemapXML generates an XML sitemap containing all posts and cheatsheets
+// sitemapSettings holds the change frequency and priority used for a collection's index page and its documents.
+type sitemapSettings struct {
+	IndexChangeFreq string
+	ChangeFreq      string
+	Priority        string
+}
+
+// collectionSitemapSettings maps collection names to their sitemap settings. Unlisted collections use defaultSitemapSettings.
+var collectionSitemapSettings = map[string]sitemapSettings{
+	"posts":       {IndexChangeFreq: "daily", ChangeFreq: "never", Priority: "0.7"},
+	"cheatsheets": {IndexChangeFreq: "weekly", ChangeFreq: "monthly", Priority: "0.8"},
+}
+
+// defaultSitemapSettings applies to collections without an entry in collectionSitemapSettings.
+var defaultSitemapSettings = sitemapSettings{IndexChangeFreq: "weekly", ChangeFreq: "monthly", Priority: "0.7"}
+
+// SitemapXML generates an XML sitemap containing the documents of every content collection
 func (app *Application) SitemapXML(c echo.Context) error {
-	posts := app.ContentManager.GetAll()
-	cheatsheets := app.CheatsheetManager.GetAll()
+	collections := app.ContentManager.All()
 
 	// Create URLSet with proper namespace
 	urlSet := URLSet{
@@ -90,52 +106,41 @@ func (app *Application) SitemapXML(c echo.Context) error {
 
 	// Add homepage
 	urlSet.URLs = append(urlSet.URLs, URLEntry{
-		Loc:        site.URL,
+		Loc:        app.site.URL,
 		LastMod:    time.Now().Format("2006-01-02"),
 		ChangeFreq: "weekly",
 		Priority:   "1.0",
 	})
 
 	// Add main sections
-	urlSet.URLs = append(urlSet.URLs, URLEntry{
-		Loc:        site.URL + "/posts",
-		LastMod:    time.Now().Format("2006-01-02"),
-		ChangeFreq: "daily",
-		Priority:   "0.9",
-	})
-
-	urlSet.URLs = append(urlSet.URLs, URLEntry{
-		Loc:        site.URL + "/cheatsheets",
-		LastMod:    time.Now().Format("2006-01-02"),
-		ChangeFreq: "weekly",
-		Priority:   "0.9",
-	})
+	for _, collection := range collections {
+		settings := sitemapSettingsFor(collection.Name())
+		urlSet.URLs = append(urlSet.URLs, URLEntry{
+			Loc:        app.site.URL + collection.Config().RoutePrefix,
+			LastMod:    time.Now().Format("2006-01-02"),
+			ChangeFreq: settings.IndexChangeFreq,
+			Priority:   "0.9",
+		})
+	}
 
 	urlSet.URLs = append(urlSet.URLs, URLEntry{
-		Loc:        site.URL + "/about",
+		Loc:        app.site.URL + "/about",
 		LastMod:    time.Now().Format("2006-01-02"),
 		ChangeFreq: "monthly",
 		Priority:   "0.8",
 	})
 
-	// Add posts
-	for _, post := range posts {
-		urlSet.URLs = append(urlSet.URLs, URLEntry{
-			Loc:        site.URL + "/posts/" + post.Slug,
-			LastMod:    post.Date.Format("2006-01-02"),
-			ChangeFreq: "never",
-			Priority:   "0.7",
-		})
-	}
-
-	// Add cheatsheets
-	for _, cs := range cheatsheets {
-		urlSet.URLs = append(urlSet.URLs, URLEntry{
-			Loc:        site.URL + "/cheatsheets/" + cs.Slug,
-			LastMod:    cs.Date.Format("2006-01-02"),
-			ChangeFreq: "monthly",
-			Priority:   "0.8",
-		})
+	// Add the documents of each collection
+	for _, collection := range collections {
+		settings := sitemapSettingsFor(collection.Name())
+		for _, doc := range collection.GetAll() {
+			urlSet.URLs = append(urlSet.URLs, URLEntry{
+				Loc:        app.site.URL + collection.Config().RoutePrefix + "/" + doc.Slug,
+				LastMod:    doc.Date.Format("2006-01-02"),
+				ChangeFreq: settings.ChangeFreq,
+				Priority:   settings.Priority,
+			})
+		}
 	}
 
 	// Marshal to XML with proper header
@@ -154,3 +159,24 @@ func (app *Application) SitemapXML(c echo.Context) error {
 	// IMPORTANT: Use String() instead of Blob to avoid gzip issues
 	return c.String(200, xmlContent)
 }
+
+// RobotsTxt writes the site's robots.txt, which keeps pull request previews out of search engines and points them at
+// the sitemap under the site's URL if the sitemap is enabled.
+func (app *Application) RobotsTxt(c echo.Context) error {
+	var robots strings.Builder
+	robots.WriteString("User-agent: *\nAllow: /\nDisallow: /preview/\n")
+	if app.Config.Features.Sitemap {
+		robots.WriteString("\nSitemap: " + app.site.URL + "/sitemap.xml\n")
+	}
+
+	return c.String(http.StatusOK, robots.String())
+}
+
+// sitemapSettingsFor returns the sitemap settings for the named collection.
+func sitemapSettingsFor(name string) sitemapSettings {
+	if settings, exists := collectionSitemapSettings[name]; exists {
+		return settings
+	}
+
+	return defaultSitemapSettings
+}
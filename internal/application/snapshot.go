@@ -0,0 +1,107 @@
+package application
+
+import (
+	"log"
+
+	"github.com/jgndev/jgn.dev/internal/bundle"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+)
+
+// loadContent refreshes every collection from its source and persists the result to the snapshot.
+func (app *Application) loadContent() {
+	for _, collection := range app.ContentManager.All() {
+		report, err := collection.RefreshContent(app.ctx)
+		if err != nil {
+			log.Printf("Failed to load initial %s: %v", collection.Name(), err)
+			continue
+		}
+
+		for _, file := range report.Failed() {
+			log.Printf("Failed to load initial %s file %s: %s", collection.Name(), file.Path, file.Error)
+		}
+	}
+
+	app.saveSnapshot()
+}
+
+// restoreSnapshot loads the site's content snapshot, configured by cache.snapshot_path, into the collections.
+// Returns whether any collection was restored.
+func (app *Application) restoreSnapshot() bool {
+	if app.snapshotPath == "" {
+		return false
+	}
+
+	snapshot, err := contentmanager.LoadSnapshot(app.snapshotPath)
+	if err != nil {
+		log.Printf("No usable content snapshot at %s: %v", app.snapshotPath, err)
+		return false
+	}
+
+	if !app.ownsSnapshot(snapshot) {
+		log.Printf("Ignoring content snapshot at %s, it belongs to site %s", app.snapshotPath, snapshot.Site)
+		return false
+	}
+
+	restored := app.ContentManager.Restore(snapshot)
+	log.Printf("Restored %v from content snapshot created at %s", restored, snapshot.CreatedAt)
+
+	return len(restored) > 0
+}
+
+// restoreBundle loads the content bundle embedded at build time into the collections. A content snapshot saved
+// while serving the same bundle is restored instead, since it holds the bundle with the webhook refreshes applied
+// on top of it; snapshots of other bundles or of the sources are older deployments and are ignored.
+// Returns whether any collection was restored.
+func (app *Application) restoreBundle() bool {
+	snapshot, ok, err := bundle.Load()
+	if err != nil {
+		log.Printf("Failed to load embedded content bundle: %v", err)
+		return false
+	}
+
+	if !ok || !app.ownsSnapshot(snapshot) {
+		return false
+	}
+
+	id := bundle.ID()
+	if app.snapshotPath != "" {
+		if saved, err := contentmanager.LoadSnapshot(app.snapshotPath); err == nil && saved.Bundle == id && app.ownsSnapshot(saved) {
+			if restored := app.ContentManager.Restore(saved); len(restored) > 0 {
+				log.Printf("Restored %v from content snapshot of embedded bundle %s created at %s", restored, id, saved.CreatedAt)
+				app.bundle = id
+				return true
+			}
+		}
+	}
+
+	restored := app.ContentManager.Restore(snapshot)
+	log.Printf("Restored %v from embedded content bundle %s created at %s", restored, id, snapshot.CreatedAt)
+	if len(restored) == 0 {
+		return false
+	}
+
+	app.bundle = id
+	app.saveSnapshot()
+	return true
+}
+
+// saveSnapshot writes the current content of every collection to the snapshot, if one is configured.
+func (app *Application) saveSnapshot() {
+	if app.snapshotPath == "" {
+		return
+	}
+
+	snapshot := app.ContentManager.Snapshot()
+	snapshot.Site = app.Config.Site.Name
+	snapshot.Bundle = app.bundle
+
+	if err := contentmanager.SaveSnapshot(app.snapshotPath, snapshot); err != nil {
+		log.Printf("Failed to save content snapshot: %v", err)
+	}
+}
+
+// ownsSnapshot reports whether the snapshot holds the content of the site the application serves.
+// Snapshots written before sites were recorded are assumed to belong to it.
+func (app *Application) ownsSnapshot(snapshot contentmanager.Snapshot) bool {
+	return snapshot.Site == "" || snapshot.Site == app.Config.Site.Name
+}
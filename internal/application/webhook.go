@@ -3,43 +3,115 @@ package application
 import (
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
-	"github.com/jgndev/jgn.dev/internal/site"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
 	"github.com/labstack/echo/v4"
 )
 
-// GitHubWebhookPayload represents the data structure of a payload received from a GitHub webhook event.
+// WebhookCommit lists the files a pushed commit added, modified and removed.
+// GitHub, GitLab and Gitea all describe commits this way.
+type WebhookCommit struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+// WebhookRepository identifies the repository a webhook event is about.
+type WebhookRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// Path returns the owner and name of the repository, e.g. "jgndev/posts", which collections are matched against.
+// Falls back to the name for payloads that don't include the full name.
+func (repo WebhookRepository) Path() string {
+	if repo.FullName != "" {
+		return repo.FullName
+	}
+	return repo.Name
+}
+
+// GitHubWebhookPayload represents the data structure of a payload received from a GitHub push event.
+// Gitea push payloads use the same structure.
 type GitHubWebhookPayload struct {
-	Ref     string `json:"ref"`
-	Commits []struct {
-		Added    []string `json:"added"`
-		Modified []string `json:"modified"`
-		Removed  []string `json:"removed"`
-	} `json:"commits"`
-	Repository struct {
-		Name     string `json:"name"`
-		FullName string `json:"full_name"`
-	} `json:"repository"`
-}
-
-// WebhookHandler handles incoming GitHub webhook requests, verifying the signature and processing relevant events.
-func (app *Application) WebhookHandler(c echo.Context) error {
-	// Get the webhook secret from the environment
-	secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if secret == "" {
-		log.Printf("Webhook received but no GITHUB_WEBHOOK_SECRET configured")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "webhook secret not configured",
-		})
+	Ref        string            `json:"ref"`   // Git ref the push updated, e.g. "refs/heads/main"
+	After      string            `json:"after"` // Commit SHA the ref points to after the push
+	Commits    []WebhookCommit   `json:"commits"`
+	Repository WebhookRepository `json:"repository"`
+}
+
+// PingPayload represents the ping event GitHub sends when a webhook is created.
+type PingPayload struct {
+	Zen        string            `json:"zen"`
+	HookID     int64             `json:"hook_id"`
+	Repository WebhookRepository `json:"repository"`
+}
+
+// ReleasePayload represents a GitHub release event.
+type ReleasePayload struct {
+	Action  string `json:"action"` // e.g. "published", "edited" or "deleted"
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+	Repository WebhookRepository `json:"repository"`
+}
+
+// RepositoryPayload represents a GitHub repository event.
+type RepositoryPayload struct {
+	Action  string `json:"action"` // e.g. "renamed", "transferred", "archived" or "deleted"
+	Changes struct {
+		Repository struct {
+			Name struct {
+				From string `json:"from"`
+			} `json:"name"`
+		} `json:"repository"`
+	} `json:"changes"`
+	Repository WebhookRepository `json:"repository"`
+}
+
+// webhookResponse is how a webhook delivery is answered: a status and a JSON body, or no body if body is nil.
+type webhookResponse struct {
+	status int
+	body   any
+}
+
+// write sends the response to the webhook's sender.
+func (resp webhookResponse) write(c echo.Context) error {
+	if resp.body == nil {
+		return c.NoContent(resp.status)
 	}
+	return c.JSON(resp.status, resp.body)
+}
+
+// webhookEventHandler handles a GitHub event, parsing its own payload from the verified request body.
+type webhookEventHandler func(app *Application, header http.Header, body []byte) webhookResponse
+
+// webhookEvents maps the GitHub events the site acts on to their handlers.
+var webhookEvents = map[string]webhookEventHandler{
+	"ping":         (*Application).handlePing,
+	"pull_request": (*Application).handlePullRequest,
+	"push":         (*Application).handleGitHubPush,
+	"release":      (*Application).handleRelease,
+	"repository":   (*Application).handleRepository,
+}
 
+// WebhookHandler handles incoming GitHub webhook requests, verifying the signature and routing the event named by
+// the X-GitHub-Event header to its handler. Events the site doesn't act on are acknowledged with 204 No Content.
+// Every verified delivery is recorded; redeliveries of a delivery are acknowledged without processing them again,
+// and a recorded payload sent under another delivery ID is rejected with 409 Conflict, as GitHub never does that.
+// Operators re-run a delivery with ReplayWebhookDelivery instead.
+func (app *Application) WebhookHandler(c echo.Context) error {
 	// Read the request body
 	body, err := io.ReadAll(c.Request().Body)
 	if err != nil {
@@ -49,113 +121,535 @@ func (app *Application) WebhookHandler(c echo.Context) error {
 		})
 	}
 
+	// Get the secrets accepted for the repository
+	repo, err := webhookRepo(body)
+	if err != nil {
+		log.Printf("Rejected webhook: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "inconsistent repository in payload",
+		})
+	}
+	keys := app.webhookKeys("GITHUB_WEBHOOK_SECRET", repo)
+	if len(keys) == 0 {
+		log.Printf("Webhook received but no GITHUB_WEBHOOK_SECRET configured for %s", repo)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "webhook secret not configured",
+		})
+	}
+
 	// Verify the webhook signature
 	signature := c.Request().Header.Get("X-Hub-Signature-256")
-	if !verifyWebhookSignature(body, signature, secret) {
-		log.Printf("Invalid webhook signature")
+	if _, ok := matchWebhookKey("GitHub", repo, keys, func(secret string) bool {
+		return verifyWebhookSignature(body, signature, secret)
+	}); !ok {
+		log.Printf("Invalid webhook signature for %s", repo)
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "invalid signature",
 		})
 	}
 
-	// Parse the webhook payload
+	// Requests without an event header are treated as pushes, like before events were routed
+	event := c.Request().Header.Get("X-GitHub-Event")
+	if event == "" {
+		event = "push"
+	}
+
+	id := c.Request().Header.Get("X-GitHub-Delivery")
+	if id == "" {
+		id = newDeliveryID()
+	}
+
+	delivery := newDelivery(id, event, body)
+	if previous := app.deliveries.claim(delivery); previous != nil {
+		// GitHub redeliveries keep the delivery ID, anything else sending a recorded payload again is a replay
+		if previous.ID != id {
+			log.Printf("Rejected %s delivery %s replaying the payload of delivery %s", event, id, previous.ID)
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error":    "payload already delivered",
+				"delivery": previous.ID,
+			})
+		}
+
+		log.Printf("Ignoring duplicate %s delivery %s", event, id)
+		return c.JSON(http.StatusOK, map[string]string{
+			"message":  "duplicate delivery ignored",
+			"delivery": previous.ID,
+			"job":      previous.Job,
+		})
+	}
+
+	return app.runDelivery(delivery, c.Request().Header).write(c)
+}
+
+// processWebhook runs the handler of a verified GitHub event and returns how the delivery is answered. Events the
+// site doesn't act on are acknowledged with 204 No Content. Both the webhook endpoint and replays process events
+// with it.
+func (app *Application) processWebhook(event string, header http.Header, body []byte) webhookResponse {
+	handler, exists := webhookEvents[event]
+	if !exists {
+		log.Printf("Ignoring GitHub %s event", event)
+		return webhookResponse{http.StatusNoContent, nil}
+	}
+
+	return handler(app, header, body)
+}
+
+// handlePing answers the ping GitHub sends when the webhook is created, confirming the secret is correct.
+func (app *Application) handlePing(header http.Header, body []byte) webhookResponse {
+	var payload PingPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse ping payload: %v", err)
+		return webhookResponse{http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		}}
+	}
+
+	log.Printf("Webhook %d pinged from %s: %s", payload.HookID, payload.Repository.FullName, payload.Zen)
+	return webhookResponse{http.StatusOK, map[string]interface{}{
+		"message": "pong",
+		"hookId":  payload.HookID,
+	}}
+}
+
+// handleGitHubPush parses a GitHub push event and queues a refresh of the pushed repository's collections.
+func (app *Application) handleGitHubPush(header http.Header, body []byte) webhookResponse {
 	var payload GitHubWebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
 		log.Printf("Failed to parse webhook payload: %v", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
+		return webhookResponse{http.StatusBadRequest, map[string]string{
 			"error": "failed to parse payload",
-		})
+		}}
 	}
 
-	// Check if this is a push to the main branch
-	if payload.Ref != "refs/heads/main" && payload.Ref != "refs/heads/master" {
-		log.Printf("Webhook received for non-main branch: %s", payload.Ref)
-		return c.JSON(http.StatusOK, map[string]string{
-			"message": "ignoring non-main branch push",
-		})
+	return app.handlePush(payload, false)
+}
+
+// handleRelease queues pinning the collections that follow the repository's releases to the tag of a newly
+// published release. Drafts and prereleases are ignored, as are edits and deletions of releases.
+func (app *Application) handleRelease(header http.Header, body []byte) webhookResponse {
+	var payload ReleasePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse release payload: %v", err)
+		return webhookResponse{http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		}}
 	}
 
-	// Check if any Markdown files were added or modified
-	hasMarkdownChanges := false
-	for _, commit := range payload.Commits {
-		for _, file := range append(commit.Added, commit.Modified...) {
-			if strings.HasSuffix(strings.ToLower(file), ".md") {
-				hasMarkdownChanges = true
-				log.Printf("Detected markdown file change: %s", file)
-				break
-			}
+	release := payload.Release
+	if payload.Action != "published" || release.Draft || release.Prerelease || release.TagName == "" {
+		log.Printf("Ignoring %s release %s of %s", payload.Action, release.TagName, payload.Repository.FullName)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "ignoring release that was not published",
+		}}
+	}
+
+	following := false
+	for _, collection := range app.ContentManager.ForRepo(payload.Repository.Path()) {
+		following = following || collection.Config().FollowReleases
+	}
+	if !following {
+		log.Printf("Release %s of %s received but no collection follows its releases", release.TagName, payload.Repository.FullName)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "no collection follows releases of this repository",
+		}}
+	}
+
+	job := app.enqueueRelease(payload)
+
+	return webhookResponse{http.StatusAccepted, map[string]interface{}{
+		"message":    "content refresh queued",
+		"repository": payload.Repository.Path(),
+		"ref":        release.TagName,
+		"job":        job.ID,
+		"status":     job.Status,
+		"statusUrl":  "/webhook/jobs/" + job.ID,
+	}}
+}
+
+// handleRepository keeps webhooks working when a content repository is renamed, and logs other changes to it
+// that need the configuration to be updated.
+func (app *Application) handleRepository(header http.Header, body []byte) webhookResponse {
+	var payload RepositoryPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse repository payload: %v", err)
+		return webhookResponse{http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		}}
+	}
+
+	repo := payload.Repository
+	switch payload.Action {
+	case "renamed":
+		// The event only carries the old name; the owner is unchanged by a rename
+		from := payload.Changes.Repository.Name.From
+		if owner, _, ok := strings.Cut(repo.FullName, "/"); ok {
+			from = owner + "/" + from
 		}
-		if hasMarkdownChanges {
+		collections := app.ContentManager.RenameRepo(from, repo.Path())
+		if len(collections) == 0 {
+			log.Printf("Repository %s renamed to %s, no collection reads from it", from, repo.FullName)
+			return webhookResponse{http.StatusOK, map[string]string{
+				"message": "no collection reads from this repository",
+			}}
+		}
+
+		// GitHub redirects API requests for the old name, so the sources keep working until the config is updated
+		log.Printf("WARNING: Repository %s renamed to %s, update the repo of its collections in the config", from, repo.FullName)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "repository rename recorded",
+		}}
+	case "transferred", "archived", "deleted":
+		if len(app.ContentManager.ForRepo(repo.Path())) > 0 {
+			log.Printf("WARNING: Content repository %s was %s, its collections may stop refreshing", repo.FullName, payload.Action)
+		}
+	default:
+		log.Printf("Ignoring %s event for repository %s", payload.Action, repo.FullName)
+	}
+
+	return webhookResponse{http.StatusOK, map[string]string{
+		"message": "repository event received",
+	}}
+}
+
+// handlePush queues a refresh of the collections backed by the pushed repository and responds with the job's ID.
+// If truncated is set, the payload does not list every pushed commit, so the collections are refreshed in full.
+func (app *Application) handlePush(payload GitHubWebhookPayload, truncated bool) webhookResponse {
+	// Check if the push updated a ref the repository's collections read
+	tracked := false
+	collections := app.ContentManager.ForRepo(payload.Repository.Path())
+	for _, collection := range collections {
+		tracked = tracked || tracksPush(collection, payload)
+	}
+	if len(collections) == 0 {
+		tracked = isDefaultBranch(payload)
+	}
+
+	if !tracked {
+		log.Printf("Webhook received for untracked ref: %s", payload.Ref)
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "ignoring push to untracked ref",
+		}}
+	}
+
+	// Check if any document or asset of the collections was added, modified or removed. Pushes to unknown
+	// repositories refresh every collection, so they are checked against every collection.
+	if len(collections) == 0 {
+		collections = app.ContentManager.All()
+	}
+	changed, removed := pushChanges(payload)
+
+	hasContentChanges := false
+	for _, file := range append(changed, removed...) {
+		if slices.ContainsFunc(collections, func(collection *contentmanager.Collection) bool {
+			return collection.IsContentPath(file)
+		}) {
+			hasContentChanges = true
+			log.Printf("Detected content file change: %s", file)
 			break
 		}
 	}
 
-	if !hasMarkdownChanges {
-		log.Printf("Webhook received but no markdown files changed")
-		return c.JSON(http.StatusOK, map[string]string{
-			"message": "no markdown files changed",
-		})
+	if !hasContentChanges && !truncated {
+		log.Printf("Webhook received but no content files changed")
+		return webhookResponse{http.StatusOK, map[string]string{
+			"message": "no content files changed",
+		}}
 	}
 
-	// Determine which content manager to refresh based on repository
-	repoName := payload.Repository.Name
-	log.Printf("Refreshing content due to webhook from %s (repo: %s)", payload.Repository.FullName, repoName)
-	
-	// Import site package to access repository names
+	// Refresh in the background, since providers give up on webhook deliveries after about 10 seconds
+	job := app.enqueueRefresh(payload, truncated)
+
+	return webhookResponse{http.StatusAccepted, map[string]interface{}{
+		"message":    "content refresh queued",
+		"repository": payload.Repository.Path(),
+		"job":        job.ID,
+		"status":     job.Status,
+		"statusUrl":  "/webhook/jobs/" + job.ID,
+	}}
+}
+
+// tracksPush reports whether a push updated the ref the collection reads. Collections following releases only
+// track releases.
+func tracksPush(collection *contentmanager.Collection, payload GitHubWebhookPayload) bool {
+	if collection.Config().FollowReleases {
+		return false
+	}
+
+	ref := collection.Ref()
+	if ref == "" {
+		return isDefaultBranch(payload)
+	}
+
+	// Refs may be configured as a branch or tag name, or in full, e.g. "refs/heads/develop"
+	return payload.Ref == ref || payload.Ref == "refs/heads/"+ref || payload.Ref == "refs/tags/"+ref
+}
+
+// isDefaultBranch reports whether a push updated the repository's default branch, or main or master if the
+// payload doesn't name it.
+func isDefaultBranch(payload GitHubWebhookPayload) bool {
+	if branch := payload.Repository.DefaultBranch; branch != "" {
+		return payload.Ref == "refs/heads/"+branch
+	}
+
+	return payload.Ref == "refs/heads/main" || payload.Ref == "refs/heads/master"
+}
+
+// runRefreshJob refreshes the collections backed by the job's repository, recording progress and the report of
+// each collection in the job as it goes.
+func (app *Application) runRefreshJob(job *RefreshJob) {
+	payload, truncated := job.payload, job.truncated
+	changed, removed := pushChanges(payload)
+
+	// Determine which collections to refresh based on repository
+	repoName := job.Repository
+	log.Printf("Refreshing content due to webhook from %s (repo: %s, job: %s)", payload.Repository.FullName, repoName, job.ID)
+
+	matched := app.ContentManager.ForRepo(repoName)
+
+	// If a pushed repository wasn't recognized, refresh every collection as fallback
+	fallback := len(matched) == 0 && job.pushed
+	if fallback {
+		log.Printf("WARNING: Unknown repository '%s', refreshing all collections as fallback", repoName)
+		matched = app.ContentManager.All()
+	}
+
+	// Collections following releases are only pinned by releases, the others only refreshed by pushes to their ref
+	var collections []*contentmanager.Collection
+	for _, collection := range matched {
+		switch {
+		case collection.Config().FollowReleases:
+			if job.Ref != "" {
+				collections = append(collections, collection)
+			}
+		case fallback || job.pushed && tracksPush(collection, payload):
+			collections = append(collections, collection)
+		}
+	}
+
+	app.jobs.update(func() {
+		for _, collection := range collections {
+			job.Collections = append(job.Collections, collection.Name())
+		}
+	})
+
+	// Only fetch the files listed in the push, unless the collections or the changes are not known precisely
+	incremental := !fallback && !truncated && len(payload.Commits) > 0
+
+	// Refreshes are bounded by each collection's RefreshTimeout and stopped when the application is closed
 	var refreshErr error
-	refreshed := false
-	
-	// Check if this is the posts repository
-	if repoName == site.PostRepoName {
-		log.Printf("Detected posts repository, refreshing ContentManager")
-		refreshErr = app.ContentManager.RefreshContent()
-		refreshed = true
-	}
-	
-	// Check if this is the cheatsheets repository
-	if repoName == site.CheatsheetRepoName {
-		log.Printf("Detected cheatsheets repository, refreshing CheatsheetManager")
-		refreshErr = app.CheatsheetManager.RefreshContent()
-		refreshed = true
-	}
-	
-	// If repository wasn't recognized, refresh both managers as fallback
-	if !refreshed {
-		log.Printf("WARNING: Unknown repository '%s', refreshing both managers as fallback", repoName)
-		
-		// Try to refresh posts first
-		if err := app.ContentManager.RefreshContent(); err != nil {
-			log.Printf("Failed to refresh posts during fallback: %v", err)
-			refreshErr = err
+	for _, collection := range collections {
+		var report *contentmanager.RefreshReport
+		var err error
+		if collection.Config().FollowReleases {
+			report, err = collection.PinRef(app.ctx, job.Ref)
+		} else if incremental {
+			log.Printf("Applying %d changed and %d removed files to %s collection", len(changed), len(removed), collection.Name())
+			report, err = collection.ApplyChanges(app.ctx, changed, removed, payload.After)
 		} else {
-			log.Printf("Successfully refreshed posts during fallback")
+			log.Printf("Refreshing %s collection", collection.Name())
+			report, err = collection.RefreshContent(app.ctx)
 		}
-		
-		// Try to refresh cheatsheets
-		if err := app.CheatsheetManager.RefreshContent(); err != nil {
-			log.Printf("Failed to refresh cheatsheets during fallback: %v", err)
+
+		// An interrupted refresh still reports the files it loaded
+		app.jobs.update(func() {
+			job.Completed++
+			if report != nil {
+				job.Reports = append(job.Reports, report)
+				job.Failed += len(report.Failed())
+			}
+		})
+
+		if err != nil {
+			log.Printf("Failed to refresh %s: %v", collection.Name(), err)
 			if refreshErr == nil {
 				refreshErr = err
 			}
+			continue
+		}
+
+		if fallback {
+			log.Printf("Successfully refreshed %s during fallback", collection.Name())
+		}
+	}
+
+	// Persist whatever was refreshed so a restart serves it immediately
+	app.saveSnapshot()
+
+	app.jobs.update(func() {
+		switch {
+		case refreshErr != nil:
+			// Handle refresh errors
+			log.Printf("Failed to refresh content for repository %s: %v", repoName, refreshErr)
+			job.Status = JobFailed
+			job.Error = refreshErr.Error()
+		case job.Failed > 0:
+			// Files that failed to load keep their previous version, the rest of the push is live
+			log.Printf("Refreshed content from webhook for repository %s with %d failed files", repoName, job.Failed)
+			job.Status = JobPartial
+		default:
+			log.Printf("Successfully refreshed content from webhook for repository: %s", repoName)
+			job.Status = JobSucceeded
+		}
+	})
+}
+
+// pushChanges collects the files added, modified and removed by the commits of a push, in commit order,
+// so a file that is modified and later removed is only reported as removed, and vice versa.
+func pushChanges(payload GitHubWebhookPayload) (changed, removed []string) {
+	state := make(map[string]bool) // true if the file exists after the push
+	var order []string
+
+	for _, commit := range payload.Commits {
+		for _, file := range append(commit.Added, commit.Modified...) {
+			if _, seen := state[file]; !seen {
+				order = append(order, file)
+			}
+			state[file] = true
+		}
+		for _, file := range commit.Removed {
+			if _, seen := state[file]; !seen {
+				order = append(order, file)
+			}
+			state[file] = false
+		}
+	}
+
+	for _, file := range order {
+		if state[file] {
+			changed = append(changed, file)
 		} else {
-			log.Printf("Successfully refreshed cheatsheets during fallback")
+			removed = append(removed, file)
 		}
 	}
-	
-	// Handle refresh errors
-	if refreshErr != nil {
-		log.Printf("Failed to refresh content for repository %s: %v", repoName, refreshErr)
+
+	return changed, removed
+}
+
+// GitLabWebhookPayload represents the data structure of a push event received from a GitLab webhook.
+type GitLabWebhookPayload struct {
+	Ref               string          `json:"ref"`
+	After             string          `json:"after"`
+	TotalCommitsCount int             `json:"total_commits_count"`
+	Commits           []WebhookCommit `json:"commits"`
+	Project           struct {
+		Name              string `json:"name"`
+		PathWithNamespace string `json:"path_with_namespace"`
+		DefaultBranch     string `json:"default_branch"`
+	} `json:"project"`
+}
+
+// GitLabWebhookHandler handles incoming GitLab push webhooks, verifying the secret token against the secrets
+// accepted for the project (see webhookKeys, GITLAB_WEBHOOK_SECRET) and refreshing the collections backed by it.
+func (app *Application) GitLabWebhookHandler(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Failed to read webhook body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "failed to read request body",
+		})
+	}
+
+	repo := gitlabProjectPath(body)
+	keys := app.webhookKeys("GITLAB_WEBHOOK_SECRET", repo)
+	if len(keys) == 0 {
+		log.Printf("GitLab webhook received but no GITLAB_WEBHOOK_SECRET configured for %s", repo)
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "failed to refresh content",
-			"repository": repoName,
+			"error": "webhook secret not configured",
 		})
 	}
 
-	log.Printf("Successfully refreshed content from webhook for repository: %s", repoName)
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "content refreshed successfully",
-		"repository": repoName,
-	})
+	// GitLab sends the secret token itself rather than a signature
+	token := c.Request().Header.Get("X-Gitlab-Token")
+	if _, ok := matchWebhookKey("GitLab", repo, keys, func(secret string) bool {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}); !ok {
+		log.Printf("Invalid GitLab webhook token for %s", repo)
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "invalid token",
+		})
+	}
+
+	if event := c.Request().Header.Get("X-Gitlab-Event"); event != "Push Hook" {
+		log.Printf("Ignoring GitLab %s event", event)
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "ignoring non-push event",
+		})
+	}
+
+	var gitlabPayload GitLabWebhookPayload
+	if err := json.Unmarshal(body, &gitlabPayload); err != nil {
+		log.Printf("Failed to parse GitLab webhook payload: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		})
+	}
+
+	payload := GitHubWebhookPayload{
+		Ref:     gitlabPayload.Ref,
+		After:   gitlabPayload.After,
+		Commits: gitlabPayload.Commits,
+	}
+	payload.Repository.Name = gitlabPayload.Project.Name
+	payload.Repository.FullName = gitlabPayload.Project.PathWithNamespace
+	payload.Repository.DefaultBranch = gitlabPayload.Project.DefaultBranch
+
+	// GitLab only includes the first 20 commits of a push
+	return app.handlePush(payload, gitlabPayload.TotalCommitsCount > len(gitlabPayload.Commits)).write(c)
+}
+
+// GiteaWebhookHandler handles incoming Gitea push webhooks, verifying the signature against the secrets accepted
+// for the repository (see webhookKeys, GITEA_WEBHOOK_SECRET) and refreshing the collections backed by it.
+func (app *Application) GiteaWebhookHandler(c echo.Context) error {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		log.Printf("Failed to read webhook body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "failed to read request body",
+		})
+	}
+
+	repo, err := webhookRepo(body)
+	if err != nil {
+		log.Printf("Rejected Gitea webhook: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "inconsistent repository in payload",
+		})
+	}
+	keys := app.webhookKeys("GITEA_WEBHOOK_SECRET", repo)
+	if len(keys) == 0 {
+		log.Printf("Gitea webhook received but no GITEA_WEBHOOK_SECRET configured for %s", repo)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "webhook secret not configured",
+		})
+	}
+
+	// Gitea signs the body like GitHub, but sends the hex digest without the "sha256=" prefix
+	signature := c.Request().Header.Get("X-Gitea-Signature")
+	if _, ok := matchWebhookKey("Gitea", repo, keys, func(secret string) bool {
+		return signature != "" && verifyWebhookSignature(body, "sha256="+signature, secret)
+	}); !ok {
+		log.Printf("Invalid Gitea webhook signature for %s", repo)
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "invalid signature",
+		})
+	}
+
+	if event := c.Request().Header.Get("X-Gitea-Event"); event != "push" {
+		log.Printf("Ignoring Gitea %s event", event)
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "ignoring non-push event",
+		})
+	}
+
+	var payload GitHubWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Failed to parse Gitea webhook payload: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "failed to parse payload",
+		})
+	}
+
+	return app.handlePush(payload, false).write(c)
 }
 
 // verifyWebhookSignature validates a webhook payload signature against the expected HMAC-SHA256 signature.
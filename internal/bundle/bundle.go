@@ -0,0 +1,43 @@
+package bundle
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+)
+
+// content is the content snapshot embedded at build time. It is generated by "server bundle" and is empty
+// in source checkouts, in which case the server loads its content from the configured sources instead.
+//
+//go:embed content.json
+var content []byte
+
+// Path is where "server bundle" writes the snapshot so it is embedded by the next build, relative to the repository root.
+const Path = "internal/bundle/content.json"
+
+// Load returns the embedded content snapshot. The boolean is false if the binary was built without a bundle.
+func Load() (contentmanager.Snapshot, bool, error) {
+	if len(content) == 0 {
+		return contentmanager.Snapshot{}, false, nil
+	}
+
+	snapshot, err := contentmanager.DecodeSnapshot(content)
+	if err != nil {
+		return contentmanager.Snapshot{}, false, err
+	}
+
+	return snapshot, true, nil
+}
+
+// ID identifies the embedded bundle, so snapshots can record which bundle their content started from.
+// Returns an empty string if the binary was built without a bundle.
+func ID() string {
+	if len(content) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,656 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+	"github.com/spf13/viper"
+)
+
+// Config is the runtime configuration of the site, read from a YAML or TOML file with environment overrides.
+type Config struct {
+	Site        SiteConfig         `mapstructure:"site"`
+	Server      ServerConfig       `mapstructure:"server"`
+	Collections []CollectionConfig `mapstructure:"collections"`
+	Refresh     RefreshConfig      `mapstructure:"refresh"`
+	Cache       CacheConfig        `mapstructure:"cache"`
+	Webhook     WebhookConfig      `mapstructure:"webhook"`
+	Previews    PreviewsConfig     `mapstructure:"previews"`
+	Features    FeaturesConfig     `mapstructure:"features"`
+	Sites       []HostedSiteConfig `mapstructure:"sites"` // Serve several sites by Host header instead of site and collections
+}
+
+// SiteConfig holds the site metadata rendered into every page and the sitemap.
+type SiteConfig struct {
+	Name          string   `mapstructure:"name"`           // Site name, used as generator and Open Graph site name
+	URL           string   `mapstructure:"url"`            // Canonical base URL, e.g. "https://jgn.dev"
+	Author        string   `mapstructure:"author"`         // Author metadata and copyright holder
+	Description   string   `mapstructure:"description"`    // Description metadata for search engines
+	Image         string   `mapstructure:"image"`          // Image URL shown when pages are shared, defaults to url + "/public/og-image.jpg"
+	Twitter       string   `mapstructure:"twitter"`        // Twitter handle of the site's Twitter card, e.g. "@jgndev", empty to omit it
+	Hosts         []string `mapstructure:"hosts"`          // Host names the site is served on when hosting several sites
+	WebhookSecret string   `mapstructure:"webhook_secret"` // Webhook secret, defaults to the provider's secret variable
+}
+
+// HostedSiteConfig describes one of several sites served by the same process. Requests are routed to a site by
+// their Host header; a single site without hosts serves every host no other site claims.
+type HostedSiteConfig struct {
+	Site         SiteConfig         `mapstructure:"site"`
+	Collections  []CollectionConfig `mapstructure:"collections"`
+	SnapshotPath string             `mapstructure:"snapshot_path"` // Parsed content snapshot of the site, empty to disable
+}
+
+// ServerConfig holds the HTTP server settings.
+type ServerConfig struct {
+	Listen string `mapstructure:"listen"` // Address to listen on, e.g. ":8080"
+}
+
+// CollectionConfig describes a content collection served by the site.
+type CollectionConfig struct {
+	Name           string        `mapstructure:"name"`            // Unique collection name, e.g. "posts"
+	Views          string        `mapstructure:"views"`           // Templates used to render the collection: "posts" or "cheatsheets"
+	RoutePrefix    string        `mapstructure:"route_prefix"`    // URL prefix, e.g. "/posts"
+	SearchPath     string        `mapstructure:"search_path"`     // URL of the search page, defaults to route_prefix + "/search"
+	Include        []string      `mapstructure:"include"`         // Globs of source paths parsed as documents
+	Exclude        []string      `mapstructure:"exclude"`         // Globs of source paths never parsed as documents
+	SlugFromPath   bool          `mapstructure:"slug_from_path"`  // Derive missing slugs from file paths
+	PollInterval   time.Duration `mapstructure:"poll_interval"`   // Overrides refresh.poll_interval, negative to disable
+	FollowReleases bool          `mapstructure:"follow_releases"` // Serve the latest release instead of the default branch
+	Source         SourceConfig  `mapstructure:"source"`
+}
+
+// SourceConfig describes where a collection's Markdown files are read from.
+type SourceConfig struct {
+	Type       string          `mapstructure:"type"`        // github (default), github-archive, gitlab, gitea, http or local
+	Owner      string          `mapstructure:"owner"`       // Account, organisation or GitLab group owning the repository
+	Repo       string          `mapstructure:"repo"`        // Repository holding the Markdown files
+	Dir        string          `mapstructure:"dir"`         // Local directory, for the local source
+	BaseURL    string          `mapstructure:"base_url"`    // API base URL, or the root URL of an http source
+	ArchiveURL string          `mapstructure:"archive_url"` // Tarball URL override for the github-archive source
+	Token      string          `mapstructure:"token"`       // Access token, defaults to the provider's token variable
+	Ref        string          `mapstructure:"ref"`         // Branch, tag or commit SHA to read, defaults to refresh.ref
+	GitHubApp  GitHubAppConfig `mapstructure:"github_app"`
+}
+
+// GitHubAppConfig authenticates a GitHub source as a GitHub App installation.
+type GitHubAppConfig struct {
+	AppID          int64  `mapstructure:"app_id"`
+	InstallationID int64  `mapstructure:"installation_id"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+}
+
+// RefreshConfig controls how collections are fetched from their sources.
+type RefreshConfig struct {
+	Concurrency  int           `mapstructure:"concurrency"`   // Files fetched in parallel
+	Timeout      time.Duration `mapstructure:"timeout"`       // Deadline for a whole refresh
+	PollInterval time.Duration `mapstructure:"poll_interval"` // How often sources are checked for missed changes, 0 to disable
+	Ref          string        `mapstructure:"ref"`           // Branch, tag or commit SHA repository sources read, empty for the default branch
+}
+
+// CacheConfig holds the cache policy of static assets and where fetched content is cached.
+type CacheConfig struct {
+	SnapshotPath string                   `mapstructure:"snapshot_path"` // Parsed content snapshot, empty to disable
+	ResponseDir  string                   `mapstructure:"response_dir"`  // API response cache directory, empty for memory only
+	MaxAge       map[string]time.Duration `mapstructure:"max_age"`       // Cache-Control max-age by asset kind
+}
+
+// WebhookConfig controls how webhook deliveries are verified and recorded.
+type WebhookConfig struct {
+	DeliveryTTL     time.Duration         `mapstructure:"delivery_ttl"`     // How long deliveries are remembered to ignore duplicates
+	DeliveryHistory int                   `mapstructure:"delivery_history"` // Most deliveries kept in the history
+	Secrets         []WebhookSecretConfig `mapstructure:"secrets"`          // Secrets accepted per repository
+}
+
+// WebhookSecretConfig lists the secrets accepted for webhooks from a repository. Accepting the previous secret
+// while senders are switched to the current one allows rotating it without rejecting deliveries.
+type WebhookSecretConfig struct {
+	Repo     string `mapstructure:"repo"`     // Repository owner and name, e.g. "jgndev/posts", empty for repositories without their own secrets
+	Current  string `mapstructure:"current"`  // Secret senders should sign with
+	Previous string `mapstructure:"previous"` // Secret being retired, still accepted
+	File     string `mapstructure:"file"`     // Mounted secrets file with the current secret on the first line and the previous one on the second
+}
+
+// PreviewsConfig controls the previews of pull requests against content repositories.
+type PreviewsConfig struct {
+	Secret   string        `mapstructure:"secret"`    // Key preview links are signed with, random per process if empty
+	TokenTTL time.Duration `mapstructure:"token_ttl"` // How long preview links stay valid
+	Max      int           `mapstructure:"max"`       // Most previews held at once
+
+	// Author associations, e.g. "MEMBER", whose pull requests from forks are previewed. Pull requests from
+	// branches of the repository itself are always previewed.
+	ForkAuthors []string `mapstructure:"fork_authors"`
+}
+
+// FeaturesConfig toggles optional features.
+type FeaturesConfig struct {
+	Webhooks bool `mapstructure:"webhooks"` // Accept push webhooks to refresh content
+	Sitemap  bool `mapstructure:"sitemap"`  // Serve /sitemap.xml
+	Watch    bool `mapstructure:"watch"`    // Watch local content directories and live-reload pages
+	Previews bool `mapstructure:"previews"` // Serve pull request previews under /preview/<owner>/<repo>/<number>/
+}
+
+// Asset kinds with a configurable Cache-Control max-age.
+const (
+	AssetFonts   = "fonts"
+	AssetScripts = "scripts" // CSS and JavaScript
+	AssetImages  = "images"
+	AssetText    = "text"
+	AssetXML     = "xml"
+	AssetDefault = "default"
+)
+
+// knownViews are the template sets collections can be rendered with.
+var knownViews = []string{"posts", "cheatsheets"}
+
+// reservedRoutes are the routes the server registers besides the collections' routes, which collections must not
+// shadow or be shadowed by. Routes other than the home page ending in a slash reserve every path below them too.
+var reservedRoutes = []string{
+	"/", "/about", "/favicon.ico", "/robots.txt", "/sitemap.xml", "/_livereload",
+	"/public/", "/webhook/", "/preview/", "/admin/",
+}
+
+// reservedRoute returns the reserved route the path collides with, if any.
+func reservedRoute(path string) (string, bool) {
+	for _, reserved := range reservedRoutes {
+		below := reserved != "/" && strings.HasSuffix(reserved, "/")
+		if path == reserved || below && (path+"/" == reserved || strings.HasPrefix(path, reserved)) {
+			return reserved, true
+		}
+	}
+
+	return "", false
+}
+
+// authorAssociations are the associations GitHub reports between the author of a pull request and its repository.
+var authorAssociations = []string{
+	"OWNER", "MEMBER", "COLLABORATOR", "CONTRIBUTOR", "FIRST_TIME_CONTRIBUTOR", "FIRST_TIMER", "MANNEQUIN", "NONE",
+}
+
+// defaults mirrors the settings the site shipped with before it was configurable.
+var defaults = map[string]any{
+	"site.name":                "jgn.dev",
+	"site.url":                 "https://jgn.dev",
+	"site.author":              "Jeremy Novak",
+	"site.description":         "Jeremy specializes in Cloud Engineering, DevOps, Education and Consulting",
+	"site.image":               "",
+	"site.twitter":             "@jgndev",
+	"refresh.concurrency":      contentmanager.DefaultConcurrency,
+	"refresh.timeout":          contentmanager.DefaultRefreshTimeout,
+	"refresh.poll_interval":    time.Duration(0),
+	"refresh.ref":              "",
+	"cache.snapshot_path":      "",
+	"cache.response_dir":       "",
+	"cache.max_age.fonts":      365 * 24 * time.Hour,
+	"cache.max_age.scripts":    30 * 24 * time.Hour,
+	"cache.max_age.images":     30 * 24 * time.Hour,
+	"cache.max_age.text":       24 * time.Hour,
+	"cache.max_age.xml":        time.Hour,
+	"cache.max_age.default":    7 * 24 * time.Hour,
+	"webhook.delivery_ttl":     72 * time.Hour,
+	"webhook.delivery_history": 200,
+	"features.webhooks":        true,
+	"features.sitemap":         true,
+	"features.watch":           false,
+	"features.previews":        false,
+	"previews.secret":          "",
+	"previews.token_ttl":       7 * 24 * time.Hour,
+	"previews.max":             10,
+	"previews.fork_authors":    []string{"OWNER", "MEMBER", "COLLABORATOR"},
+	"collections":              defaultCollections,
+}
+
+// defaultCollections are the collections served when the configuration file defines none.
+var defaultCollections = []map[string]any{
+	{
+		"name":         "posts",
+		"views":        "posts",
+		"route_prefix": "/posts",
+		"search_path":  "/search",
+		"source":       map[string]any{"type": contentmanager.SourceGitHub, "owner": "jgndev", "repo": "posts"},
+	},
+	{
+		"name":         "cheatsheets",
+		"views":        "cheatsheets",
+		"route_prefix": "/cheatsheets",
+		"source":       map[string]any{"type": contentmanager.SourceGitHub, "owner": "jgndev", "repo": "cheatsheets"},
+	},
+}
+
+// Load reads the configuration file named by CONFIG_FILE, or config.yaml/config.toml in the working directory
+// if it exists, applies environment overrides and validates the result. Every scalar setting can be overridden
+// with an environment variable named after its key, prefixed with JGN_, e.g. JGN_SITE_URL or JGN_SERVER_LISTEN.
+// For compatibility, PORT, CONTENT_SNAPSHOT_PATH, GITHUB_CACHE_DIR, CONTENT_WATCH, CONTENT_FETCH_CONCURRENCY and
+// CONTENT_REFRESH_TIMEOUT are honoured too.
+func Load() (*Config, error) {
+	v := viper.New()
+
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
+
+	v.SetEnvPrefix("JGN")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	// AutomaticEnv only reaches keys viper already knows when unmarshalling, so keys without a default are bound
+	if err := bindEnv(v, "", reflect.TypeOf(Config{})); err != nil {
+		return nil, err
+	}
+
+	legacyEnv := map[string]string{
+		"cache.snapshot_path": "CONTENT_SNAPSHOT_PATH",
+		"cache.response_dir":  "GITHUB_CACHE_DIR",
+		"features.watch":      "CONTENT_WATCH",
+		"refresh.concurrency": "CONTENT_FETCH_CONCURRENCY",
+		"refresh.timeout":     "CONTENT_REFRESH_TIMEOUT",
+	}
+	for key, env := range legacyEnv {
+		if err := v.BindEnv(key, "JGN_"+strings.ToUpper(strings.ReplaceAll(key, ".", "_")), env); err != nil {
+			return nil, err
+		}
+	}
+
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+	} else {
+		v.SetConfigName("config")
+		v.AddConfigPath(".")
+		if err := v.ReadInConfig(); err != nil {
+			var notFound viper.ConfigFileNotFoundError
+			if !errors.As(err, &notFound) {
+				return nil, fmt.Errorf("failed to read config file: %w", err)
+			}
+		}
+	}
+
+	if file := v.ConfigFileUsed(); file != "" {
+		log.Printf("Loaded configuration from %s", file)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	// Cloud Run and most platforms tell the server which port to use
+	if cfg.Server.Listen == "" {
+		cfg.Server.Listen = ":8080"
+		if port := os.Getenv("PORT"); port != "" {
+			cfg.Server.Listen = ":" + port
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// bindEnv binds the JGN_ environment variable of every scalar setting of the struct type t whose keys start with
+// prefix. Lists of structs, such as collections, can only be set in the configuration file.
+func bindEnv(v *viper.Viper, prefix string, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := prefix + field.Tag.Get("mapstructure")
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			if err := bindEnv(v, key+".", field.Type); err != nil {
+				return err
+			}
+		case field.Type.Kind() == reflect.Map,
+			field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			continue
+		default:
+			if err := v.BindEnv(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks the configuration and returns an error describing every problem found.
+func (cfg *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if cfg.Server.Listen == "" {
+		fail("server.listen: must not be empty, e.g. \":8080\"")
+	}
+
+	if cfg.Refresh.Concurrency <= 0 {
+		fail("refresh.concurrency: must be a positive number, got %d", cfg.Refresh.Concurrency)
+	}
+	if cfg.Refresh.Timeout <= 0 {
+		fail("refresh.timeout: must be a positive duration such as 2m, got %v", cfg.Refresh.Timeout)
+	}
+	if cfg.Refresh.PollInterval < 0 {
+		fail("refresh.poll_interval: must not be negative, use 0 to disable polling, got %v", cfg.Refresh.PollInterval)
+	} else if cfg.Refresh.PollInterval > 0 && cfg.Refresh.PollInterval < time.Minute {
+		fail("refresh.poll_interval: must be at least 1m to stay within API rate limits, got %v", cfg.Refresh.PollInterval)
+	}
+
+	if cfg.Webhook.DeliveryTTL <= 0 {
+		fail("webhook.delivery_ttl: must be a positive duration such as 72h, got %v", cfg.Webhook.DeliveryTTL)
+	}
+	if cfg.Webhook.DeliveryHistory <= 0 {
+		fail("webhook.delivery_history: must be a positive number, got %d", cfg.Webhook.DeliveryHistory)
+	}
+	secretRepos := make(map[string]bool)
+	for i, secrets := range cfg.Webhook.Secrets {
+		field := fmt.Sprintf("webhook.secrets[%d]", i)
+		repo := strings.ToLower(secrets.Repo)
+		if secretRepos[repo] {
+			fail("%s.repo: duplicate secrets for repository %q", field, secrets.Repo)
+		}
+		secretRepos[repo] = true
+
+		switch {
+		case secrets.Repo != "" && !strings.Contains(strings.Trim(secrets.Repo, "/"), "/"):
+			fail("%s.repo: must be the repository's owner and name, e.g. jgndev/posts, got %q", field, secrets.Repo)
+		case secrets.File != "" && (secrets.Current != "" || secrets.Previous != ""):
+			fail("%s: set either current and previous or file, not both", field)
+		case secrets.File == "" && secrets.Current == "":
+			fail("%s.current: required unless the secrets are read from a file", field)
+		}
+	}
+
+	if cfg.Previews.TokenTTL <= 0 {
+		fail("previews.token_ttl: must be a positive duration such as 168h, got %v", cfg.Previews.TokenTTL)
+	}
+	if cfg.Previews.Max <= 0 {
+		fail("previews.max: must be a positive number, got %d", cfg.Previews.Max)
+	}
+	for i, association := range cfg.Previews.ForkAuthors {
+		if !slices.Contains(authorAssociations, strings.ToUpper(association)) {
+			fail("previews.fork_authors[%d]: must be one of %s, got %q", i, strings.Join(authorAssociations, ", "), association)
+		}
+	}
+
+	for kind, maxAge := range cfg.Cache.MaxAge {
+		if maxAge < 0 {
+			fail("cache.max_age.%s: must not be negative, got %v", kind, maxAge)
+		}
+	}
+
+	if len(cfg.Sites) == 0 {
+		errs = append(errs, validateSite(cfg.Site, cfg.Collections, "")...)
+		return errors.Join(errs...)
+	}
+
+	names := make(map[string]bool)
+	hosts := make(map[string]string)
+	snapshots := make(map[string]string)
+	fallback := ""
+	for i, hosted := range cfg.Sites {
+		prefix := fmt.Sprintf("sites[%d].", i)
+		errs = append(errs, validateSite(hosted.Site, hosted.Collections, prefix)...)
+
+		if names[hosted.Site.Name] {
+			fail("%ssite.name: duplicate site %q", prefix, hosted.Site.Name)
+		}
+		names[hosted.Site.Name] = true
+
+		if len(hosted.Site.Hosts) == 0 {
+			if fallback != "" {
+				fail("%ssite.hosts: only one site may omit hosts, %s already serves unknown hosts", prefix, fallback)
+			}
+			fallback = hosted.Site.Name
+		}
+		for _, host := range hosted.Site.Hosts {
+			host = strings.ToLower(host)
+			if host == "" || strings.ContainsAny(host, ":/") {
+				fail("%ssite.hosts: must be host names without scheme or port, e.g. \"docs.example.com\", got %q", prefix, host)
+			} else if other, exists := hosts[host]; exists {
+				fail("%ssite.hosts: %s is already served by %s", prefix, host, other)
+			}
+			hosts[host] = hosted.Site.Name
+		}
+
+		if path := hosted.SnapshotPath; path != "" {
+			if other, exists := snapshots[path]; exists {
+				fail("%ssnapshot_path: %s is already used by %s", prefix, path, other)
+			}
+			snapshots[path] = hosted.Site.Name
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateSite checks the metadata and collections of a site. Field names in errors are prefixed with prefix.
+func validateSite(site SiteConfig, collections []CollectionConfig, prefix string) []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, errors.New(prefix+fmt.Sprintf(format, args...)))
+	}
+
+	if u, err := url.Parse(site.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("site.url: must be an absolute URL such as https://example.com, got %q", site.URL)
+	} else if strings.HasSuffix(site.URL, "/") {
+		fail("site.url: must not end with a slash, got %q", site.URL)
+	}
+	if u, err := url.Parse(site.Image); site.Image != "" && (err != nil || u.Scheme == "" || u.Host == "") {
+		fail("site.image: must be an absolute URL such as https://example.com/og-image.jpg, got %q", site.Image)
+	}
+	if site.Name == "" {
+		fail("site.name: must not be empty")
+	}
+	if site.Author == "" {
+		fail("site.author: must not be empty")
+	}
+
+	if len(collections) == 0 {
+		fail("collections: at least one collection is required")
+	}
+
+	names := make(map[string]bool)
+	routes := make(map[string]string)
+	for i, collection := range collections {
+		field := fmt.Sprintf("collections[%d]", i)
+
+		if collection.Name == "" {
+			fail("%s.name: must not be empty", field)
+		} else if names[collection.Name] {
+			fail("%s.name: duplicate collection %q", field, collection.Name)
+		}
+		names[collection.Name] = true
+
+		if !slices.Contains(knownViews, collection.Views) {
+			fail("%s.views: must be one of %s, got %q", field, strings.Join(knownViews, ", "), collection.Views)
+		}
+
+		if !strings.HasPrefix(collection.RoutePrefix, "/") || collection.RoutePrefix == "/" || strings.HasSuffix(collection.RoutePrefix, "/") {
+			fail("%s.route_prefix: must start but not end with a slash, e.g. \"/posts\", got %q", field, collection.RoutePrefix)
+		}
+		for _, route := range []string{collection.RoutePrefix, collection.searchPath()} {
+			if other, exists := routes[route]; exists {
+				fail("%s: route %s is already used by the %s collection", field, route, other)
+			}
+			if reserved, exists := reservedRoute(route); exists {
+				fail("%s: route %s collides with the site's %s route", field, route, reserved)
+			}
+			routes[route] = collection.Name
+		}
+
+		if collection.PollInterval > 0 && collection.PollInterval < time.Minute {
+			fail("%s.poll_interval: must be at least 1m to stay within API rate limits, got %v", field, collection.PollInterval)
+		}
+
+		if collection.FollowReleases && !collection.Source.readsRepository() {
+			fail("%s.follow_releases: requires a github, gitlab or gitea source, or a github-archive source with owner and repo", field)
+		}
+		if collection.Source.Ref != "" {
+			switch {
+			case collection.FollowReleases:
+				fail("%s.source.ref: cannot be set when following releases", field)
+			case !collection.Source.readsRepository():
+				fail("%s.source.ref: requires a github, gitlab or gitea source, or a github-archive source with owner and repo", field)
+			}
+		}
+
+		errs = append(errs, collection.Source.validate(prefix+field+".source")...)
+	}
+
+	return errs
+}
+
+// SiteConfigs returns the configuration of every site served by the process, in the order they are configured.
+// Each has the site's metadata, collections and snapshot path and shares the server, refresh, cache and feature
+// settings. Without a sites list, the configuration describes a single site and is returned as is.
+func (cfg *Config) SiteConfigs() []*Config {
+	if len(cfg.Sites) == 0 {
+		return []*Config{cfg}
+	}
+
+	configs := make([]*Config, 0, len(cfg.Sites))
+	for _, hosted := range cfg.Sites {
+		siteConfig := *cfg
+		siteConfig.Site = hosted.Site
+		siteConfig.Collections = hosted.Collections
+		siteConfig.Cache.SnapshotPath = hosted.SnapshotPath
+		siteConfig.Sites = nil
+		configs = append(configs, &siteConfig)
+	}
+
+	return configs
+}
+
+// validate checks that the source has the settings its type requires.
+func (source SourceConfig) validate(field string) []error {
+	var errs []error
+	requireRepo := func() {
+		if source.Owner == "" || source.Repo == "" {
+			errs = append(errs, fmt.Errorf("%s: owner and repo are required for %s sources", field, source.Type))
+		}
+	}
+
+	switch source.Type {
+	case contentmanager.SourceGitHub, "":
+		requireRepo()
+	case contentmanager.SourceGitHubArchive:
+		if source.ArchiveURL == "" {
+			requireRepo()
+		}
+	case contentmanager.SourceGitLab:
+		requireRepo()
+	case contentmanager.SourceGitea:
+		requireRepo()
+		if source.BaseURL == "" {
+			errs = append(errs, fmt.Errorf("%s.base_url: required for gitea sources, e.g. https://gitea.example.com/api/v1", field))
+		}
+	case contentmanager.SourceHTTP:
+		if source.BaseURL == "" {
+			errs = append(errs, fmt.Errorf("%s.base_url: required for http sources", field))
+		}
+	case contentmanager.SourceLocal:
+		if source.Dir == "" {
+			errs = append(errs, fmt.Errorf("%s.dir: required for local sources", field))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.type: unknown source type %q", field, source.Type))
+	}
+
+	if source.BaseURL != "" {
+		if u, err := url.Parse(source.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s.base_url: must be an absolute URL, got %q", field, source.BaseURL))
+		}
+	}
+
+	if app := source.GitHubApp; app.AppID != 0 && (app.InstallationID == 0 || app.PrivateKeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s.github_app: installation_id and private_key_file are required with app_id", field))
+	}
+
+	return errs
+}
+
+// searchPath returns the URL of the collection's search page.
+func (collection CollectionConfig) searchPath() string {
+	if collection.SearchPath != "" {
+		return collection.SearchPath
+	}
+	return collection.RoutePrefix + "/search"
+}
+
+// ContentConfig converts the collection to the configuration used by the content manager.
+func (cfg *Config) ContentConfig(collection CollectionConfig) contentmanager.CollectionConfig {
+	return contentmanager.CollectionConfig{
+		Name: collection.Name,
+		Source: contentmanager.SourceConfig{
+			Type:       collection.Source.Type,
+			RepoOwner:  collection.Source.Owner,
+			RepoName:   collection.Source.Repo,
+			Dir:        collection.Source.Dir,
+			ArchiveURL: collection.Source.ArchiveURL,
+			BaseURL:    collection.Source.BaseURL,
+			Token:      collection.Source.Token,
+			Ref:        collection.sourceRef(cfg.Refresh.Ref),
+			GitHubAuth: contentmanager.GitHubAuthConfig{
+				AppID:          collection.Source.GitHubApp.AppID,
+				InstallationID: collection.Source.GitHubApp.InstallationID,
+				PrivateKeyPath: collection.Source.GitHubApp.PrivateKeyFile,
+			},
+		},
+		RoutePrefix:    collection.RoutePrefix,
+		SearchPath:     collection.SearchPath,
+		Include:        collection.Include,
+		Exclude:        collection.Exclude,
+		SlugFromPath:   collection.SlugFromPath,
+		Concurrency:    cfg.Refresh.Concurrency,
+		RefreshTimeout: cfg.Refresh.Timeout,
+		PollInterval:   collection.pollInterval(cfg.Refresh.PollInterval),
+		FollowReleases: collection.FollowReleases,
+	}
+}
+
+// readsRepository reports whether the source reads a Git repository, so it can read a ref and follow releases.
+func (source SourceConfig) readsRepository() bool {
+	switch source.Type {
+	case contentmanager.SourceGitHub, contentmanager.SourceGitLab, contentmanager.SourceGitea, "":
+		return true
+	case contentmanager.SourceGitHubArchive:
+		return source.ArchiveURL == ""
+	default:
+		return false
+	}
+}
+
+// sourceRef returns the ref the collection's source reads, given the default ref of repository sources.
+func (collection CollectionConfig) sourceRef(defaultRef string) string {
+	switch {
+	case collection.Source.Ref != "":
+		return collection.Source.Ref
+	case collection.FollowReleases || !collection.Source.readsRepository():
+		return ""
+	default:
+		return defaultRef
+	}
+}
+
+// pollInterval returns how often the collection's source is polled, given the default interval.
+func (collection CollectionConfig) pollInterval(defaultInterval time.Duration) time.Duration {
+	switch {
+	case collection.PollInterval < 0:
+		return 0
+	case collection.PollInterval > 0:
+		return collection.PollInterval
+	default:
+		return defaultInterval
+	}
+}
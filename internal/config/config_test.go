@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadFile loads the configuration from a file with the given YAML content.
+func loadFile(t *testing.T, yaml string) (*Config, error) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(file, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", file)
+
+	return Load()
+}
+
+func TestLoadEnvironmentOverrides(t *testing.T) {
+	t.Setenv("JGN_SERVER_LISTEN", ":9999")
+	t.Setenv("JGN_SITE_WEBHOOK_SECRET", "s3cret")
+	t.Setenv("JGN_SITE_URL", "https://example.com")
+	t.Setenv("JGN_PREVIEWS_MAX", "3")
+	t.Setenv("GITHUB_CACHE_DIR", "/tmp/responses")
+
+	// Neither key has a default or is set in the file
+	cfg, err := loadFile(t, "site:\n  name: example\n")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Listen != ":9999" {
+		t.Errorf("server.listen = %q, want JGN_SERVER_LISTEN", cfg.Server.Listen)
+	}
+	if cfg.Site.WebhookSecret != "s3cret" {
+		t.Errorf("site.webhook_secret = %q, want JGN_SITE_WEBHOOK_SECRET", cfg.Site.WebhookSecret)
+	}
+	if cfg.Site.URL != "https://example.com" || cfg.Previews.Max != 3 {
+		t.Errorf("site.url = %q, previews.max = %d, want the environment overrides", cfg.Site.URL, cfg.Previews.Max)
+	}
+	if cfg.Cache.ResponseDir != "/tmp/responses" {
+		t.Errorf("cache.response_dir = %q, want GITHUB_CACHE_DIR", cfg.Cache.ResponseDir)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("PORT", "3000")
+
+	cfg, err := loadFile(t, "site:\n  name: example\n")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Server.Listen != ":3000" {
+		t.Errorf("server.listen = %q, want PORT", cfg.Server.Listen)
+	}
+	if cfg.Site.Name != "example" || cfg.Site.URL != "https://jgn.dev" {
+		t.Errorf("site = %+v, want the file's name and the default URL", cfg.Site)
+	}
+	if len(cfg.Collections) != 2 {
+		t.Errorf("collections = %d, want the default posts and cheatsheets", len(cfg.Collections))
+	}
+}
+
+func TestValidateReservedRoutes(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection string
+		want       string
+	}{
+		{"about", "route_prefix: /about", "collides with the site's /about route"},
+		{"admin", "route_prefix: /admin", "collides with the site's /admin/ route"},
+		{"below webhook", "route_prefix: /webhook/notes", "collides with the site's /webhook/ route"},
+		{"preview search", "route_prefix: /notes\n    search_path: /preview", "collides with the site's /preview/ route"},
+		{"home search", "route_prefix: /notes\n    search_path: /", "collides with the site's / route"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadFile(t, "collections:\n  - name: notes\n    views: posts\n    "+tt.collection+"\n    source:\n      type: local\n      dir: notes\n")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadFile(t, "collections:\n  - name: notes\n    views: posts\n    route_prefix: /administration\n    source:\n      type: local\n      dir: notes\n"); err != nil {
+		t.Errorf("Load() error = %v, want /administration to be allowed", err)
+	}
+}
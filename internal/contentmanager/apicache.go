@@ -0,0 +1,321 @@
+package contentmanager
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxCachedBodySize is the largest response body kept by the response cache, so large archives are not held twice.
+const maxCachedBodySize = 16 << 20
+
+// maxCacheSize bounds the total size of the bodies a response cache holds. The least recently used entries are
+// evicted, from memory and disk, once it is exceeded.
+const maxCacheSize = 128 << 20
+
+// apiCacheVars counts, per site, conditional requests answered from the response caches of the site's clients
+// ("hits"), responses stored in them ("stores") and entries dropped from them ("evictions"), e.g. on /admin/vars.
+var apiCacheVars = expvar.NewMap("api_cache")
+
+// cachedResponse is a successful response body together with the validators the API sent for it.
+type cachedResponse struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Body         []byte `json:"body"`
+
+	key string // Request the response is cached for
+}
+
+// responseCache keeps the bodies and validators of API responses keyed by request, in memory and optionally
+// on disk, so unchanged resources can be requested conditionally and served from the cache on 304 Not Modified.
+// It holds at most maxSize bytes of bodies, evicting the least recently used entries.
+type responseCache struct {
+	sync.Mutex
+	entries map[string]*list.Element // Elements of lru keyed by request
+	lru     *list.List               // Cached responses, most recently used first
+	size    int                      // Total size of the cached bodies
+	maxSize int
+	dir     string      // Directory the entries are persisted to, empty to keep them in memory only
+	stats   *expvar.Map // Counters of the site the cache belongs to, see apiCacheVars
+}
+
+// newResponseCache initializes and returns a pointer to an empty in-memory responseCache.
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		maxSize: maxCacheSize,
+		stats:   new(expvar.Map).Init(),
+	}
+}
+
+// setDir persists entries to the given directory from now on, creating it if needed, and serves entries written
+// there by previous runs. The least recently written entries are removed if the directory exceeds the size limit.
+func (rc *responseCache) setDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	rc.Lock()
+	rc.dir = dir
+	rc.Unlock()
+
+	return pruneCacheDir(dir, rc.maxSize)
+}
+
+// get returns the cached response for the key, loading it from disk if it is not in memory yet.
+func (rc *responseCache) get(key string) *cachedResponse {
+	rc.Lock()
+	if elem, exists := rc.entries[key]; exists {
+		rc.lru.MoveToFront(elem)
+		rc.Unlock()
+		return elem.Value.(*cachedResponse)
+	}
+	dir := rc.dir
+	rc.Unlock()
+
+	if dir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(rc.path(dir, key))
+	if err != nil {
+		return nil
+	}
+
+	entry := &cachedResponse{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		log.Printf("Ignoring corrupt API cache entry for %s: %v", key, err)
+		return nil
+	}
+	entry.key = key
+
+	rc.Lock()
+	evicted := rc.addLocked(entry)
+	rc.Unlock()
+
+	rc.remove(dir, evicted)
+	return entry
+}
+
+// put stores the response for the key in memory and, if configured, on disk.
+func (rc *responseCache) put(key string, entry *cachedResponse) {
+	entry.key = key
+
+	rc.Lock()
+	evicted := rc.addLocked(entry)
+	dir := rc.dir
+	rc.Unlock()
+
+	rc.stats.Add("stores", 1)
+
+	if dir == "" {
+		return
+	}
+
+	if err := rc.write(rc.path(dir, key), entry); err != nil {
+		log.Printf("Failed to persist API cache entry for %s: %v", entry.URL, err)
+	}
+	rc.remove(dir, evicted)
+}
+
+// addLocked inserts the entry as the most recently used one, replacing any entry for the same key, and evicts
+// the least recently used entries beyond the size limit. Returns the keys of the evicted entries.
+// The caller must hold the lock.
+func (rc *responseCache) addLocked(entry *cachedResponse) []string {
+	if elem, exists := rc.entries[entry.key]; exists {
+		rc.size -= len(elem.Value.(*cachedResponse).Body)
+		rc.lru.Remove(elem)
+	}
+
+	rc.entries[entry.key] = rc.lru.PushFront(entry)
+	rc.size += len(entry.Body)
+
+	var evicted []string
+	for rc.size > rc.maxSize && rc.lru.Len() > 1 {
+		oldest := rc.lru.Remove(rc.lru.Back()).(*cachedResponse)
+		delete(rc.entries, oldest.key)
+		rc.size -= len(oldest.Body)
+		evicted = append(evicted, oldest.key)
+	}
+
+	rc.stats.Add("evictions", int64(len(evicted)))
+	return evicted
+}
+
+// forget drops every entry whose URL matches, e.g. the responses for a ref that is no longer read.
+func (rc *responseCache) forget(match func(u *url.URL) bool) {
+	rc.Lock()
+	var forgotten []string
+	for key, elem := range rc.entries {
+		entry := elem.Value.(*cachedResponse)
+		if u, err := url.Parse(entry.URL); err == nil && match(u) {
+			rc.lru.Remove(elem)
+			delete(rc.entries, key)
+			rc.size -= len(entry.Body)
+			forgotten = append(forgotten, key)
+		}
+	}
+	dir := rc.dir
+	rc.Unlock()
+
+	rc.remove(dir, forgotten)
+}
+
+// remove deletes the persisted entries for the given keys.
+func (rc *responseCache) remove(dir string, keys []string) {
+	if dir == "" {
+		return
+	}
+
+	for _, key := range keys {
+		if err := os.Remove(rc.path(dir, key)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove API cache entry %s: %v", key, err)
+		}
+	}
+}
+
+// pruneCacheDir removes the least recently written entries of a cache directory until the remaining ones
+// take up at most maxSize bytes.
+func pruneCacheDir(dir string, maxSize int) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var infos []os.FileInfo
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		if info, err := file.Info(); err == nil {
+			infos = append(infos, info)
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	var size int64
+	for _, info := range infos {
+		size += info.Size()
+		if size <= int64(maxSize) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write saves an entry atomically, so a crash never leaves a truncated entry behind.
+func (rc *responseCache) write(path string, entry *cachedResponse) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// path returns the file an entry is persisted to, named after a hash of its key.
+func (rc *responseCache) path(dir, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// cacheKey identifies a GET request by its URL and the representation it accepts.
+func cacheKey(url, accept string) string {
+	return fmt.Sprintf("%s %s", accept, url)
+}
+
+// setConditionalHeaders asks the API to only send the resource if it differs from the cached one.
+func (entry *cachedResponse) setConditionalHeaders(req *http.Request) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// response turns a 304 Not Modified response into a 200 OK response carrying the cached body.
+func (entry *cachedResponse) response(notModified *http.Response) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        notModified.Header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       notModified.Request,
+	}
+}
+
+// storeResponse caches the body of a successful response that carries validators, replacing the body with one
+// that can still be read by the caller. Bodies larger than maxCachedBodySize are passed through uncached.
+func (rc *responseCache) storeResponse(key string, resp *http.Response) error {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBodySize+1))
+	if err != nil {
+		return err
+	}
+
+	if len(body) > maxCachedBodySize {
+		original := resp.Body
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return nil
+	}
+
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rc.put(key, &cachedResponse{
+		URL:          resp.Request.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		Body:         body,
+	})
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package contentmanager
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	rc := newResponseCache()
+	rc.maxSize = 10
+	if err := rc.setDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	rc.put("a", &cachedResponse{URL: "https://api.github.com/a", Body: []byte("aaaa")})
+	rc.put("b", &cachedResponse{URL: "https://api.github.com/b", Body: []byte("bbbb")})
+	rc.get("a") // a is now used more recently than b
+	rc.put("c", &cachedResponse{URL: "https://api.github.com/c", Body: []byte("cccc")})
+
+	if _, exists := rc.entries["b"]; exists {
+		t.Error("least recently used entry b was not evicted")
+	}
+	if _, err := os.Stat(rc.path(dir, "b")); !os.IsNotExist(err) {
+		t.Errorf("evicted entry b is still persisted: %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if rc.get(key) == nil {
+			t.Errorf("entry %s was evicted, want it kept", key)
+		}
+	}
+	if rc.size != 8 {
+		t.Errorf("size = %d, want 8", rc.size)
+	}
+}
+
+func TestResponseCacheLoadsPersistedEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	first := newResponseCache()
+	first.setDir(dir)
+	first.put("a", &cachedResponse{URL: "https://api.github.com/a", ETag: `"v1"`, Body: []byte("body")})
+
+	second := newResponseCache()
+	second.setDir(dir)
+	entry := second.get("a")
+	if entry == nil || entry.ETag != `"v1"` || string(entry.Body) != "body" {
+		t.Fatalf("get() = %+v, want the entry persisted by the previous cache", entry)
+	}
+	if second.size != 4 {
+		t.Errorf("size = %d, want loaded entries to count towards the limit", second.size)
+	}
+}
+
+func TestForgetRef(t *testing.T) {
+	client := NewAPIClient("GitHub API", GitHubAPIURL, nil)
+	urls := map[string]string{
+		"contents": client.URL("/repos/o/r/contents/posts?ref=abc123"),
+		"commits":  client.URL("/repos/o/r/commits?per_page=1&sha=abc123"),
+		"tarball":  client.URL("/repos/o/r/tarball/abc123"),
+		"gitlab":   "https://gitlab.com/api/v4/projects/1/repository/commits?ref_name=abc123",
+		"other":    client.URL("/repos/o/r/contents/posts?ref=def456"),
+		"default":  client.URL("/repos/o/r/contents/posts"),
+	}
+	for key, url := range urls {
+		client.cache.put(key, &cachedResponse{URL: url, Body: []byte(key)})
+	}
+
+	client.ForgetRef("abc123")
+
+	for key := range urls {
+		_, exists := client.cache.entries[key]
+		if want := key == "other" || key == "default"; exists != want {
+			t.Errorf("entry %s kept = %v, want %v", key, exists, want)
+		}
+	}
+}
+
+func TestPruneCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	for i, name := range []string{"old.json", "newer.json", "newest.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", 10)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modified := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pruneCacheDir(dir, 25); err != nil {
+		t.Fatalf("pruneCacheDir() error = %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if got := strings.Join(names, ","); got != "newer.json,newest.json" {
+		t.Errorf("kept %s, want the two most recently written entries", got)
+	}
+}
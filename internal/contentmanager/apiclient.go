@@ -0,0 +1,525 @@
+package contentmanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubAPIURL is the base URL of the public GitHub REST API.
+const GitHubAPIURL = "https://api.github.com"
+
+const (
+	// apiMaxRetries is how many times a request is retried after a retryable failure.
+	apiMaxRetries = 3
+
+	// apiRetryDelay is the delay before the first retry of a failed request; it doubles with every attempt.
+	apiRetryDelay = time.Second
+
+	// apiMaxRateLimitWait is the longest a request waits for a rate limit to reset before giving up,
+	// so an exhausted quota fails a refresh instead of stalling it for up to an hour.
+	apiMaxRateLimitWait = time.Minute
+
+	// apiSecondaryLimitWait is how long to back off after hitting a secondary rate limit without a Retry-After header,
+	// as recommended by GitHub.
+	apiSecondaryLimitWait = time.Minute
+)
+
+// apiRateLimitVars publishes the most recent rate limit reported to each API client, keyed by the client's label,
+// e.g. "jgn.dev/github" for the client a site reads github.com with, on /admin/vars.
+var apiRateLimitVars = expvar.NewMap("api_rate_limit")
+
+// RateLimit is the API quota reported in the headers of the most recent response.
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// APIError is returned for API responses with an unsuccessful status.
+type APIError struct {
+	API        string // Name of the API that returned the error, e.g. "GitLab API"
+	StatusCode int
+	Message    string
+}
+
+// Error formats the status and message returned by the API.
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s returned status %d", e.API, e.StatusCode)
+	}
+	return fmt.Sprintf("%s returned status %d: %s", e.API, e.StatusCode, e.Message)
+}
+
+// APIClient sends requests to a content API, retrying failures that may succeed later and waiting out rate limits
+// as instructed by the Retry-After header and the X-RateLimit-* (GitHub, Gitea) or RateLimit-* (GitLab) headers.
+// Sources reading from the same API with the same credentials share a client, so they draw from the same quota.
+type APIClient struct {
+	client     *http.Client
+	name       string // Name of the API in errors and logs, e.g. "GitHub API"
+	label      string // Key of the client's quota in the api_rate_limit metrics, prefixed with the site's name
+	baseURL    string
+	auth       TokenSource // Provides the token requests are authenticated with, nil for anonymous requests
+	authScheme string      // Scheme of the Authorization header, "token" for GitHub and Gitea
+	cache      *responseCache
+	sleep      func(ctx context.Context, d time.Duration) error // Waits between attempts, sleepContext by default
+
+	mu        sync.Mutex
+	rateLimit RateLimit
+}
+
+// NewAPIClient initializes and returns a pointer to an APIClient for the API at baseURL, named name in errors,
+// authenticating with tokens from auth, or anonymously if auth is nil.
+func NewAPIClient(name, baseURL string, auth TokenSource) *APIClient {
+	return &APIClient{
+		client:     &http.Client{},
+		name:       name,
+		label:      strings.TrimSuffix(baseURL, "/"),
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		auth:       auth,
+		authScheme: "token",
+		cache:      newResponseCache(),
+		sleep:      sleepContext,
+	}
+}
+
+// SetCacheDir persists cached responses to the given directory, so conditional requests survive restarts.
+// Responses are always cached in memory.
+func (gc *APIClient) SetCacheDir(dir string) error {
+	return gc.cache.setDir(dir)
+}
+
+// apiClientKey identifies the clients shared by sources reading from the same API with the same credentials.
+type apiClientKey struct {
+	sourceType string
+	baseURL    string
+	token      string
+	auth       GitHubAuthConfig
+}
+
+// ClientPool holds the API clients of a site. Sources of the site reading from the same API with the same
+// credentials share a client, so they draw from the same quota and cache. Every site has its own pool, so sites
+// served by the same process never share cached responses, rate limit state or metrics.
+type ClientPool struct {
+	sync.Mutex
+	site     string // Name of the site, prefixing the labels of its clients in the api_rate_limit metrics
+	cacheDir string // Directory the clients persist cached responses to, empty to keep them in memory only
+	clients  map[apiClientKey]*APIClient
+	stats    *expvar.Map // The site's api_cache counters
+}
+
+// NewClientPool initializes and returns a pointer to an empty ClientPool for the named site, whose clients persist
+// cached responses below cacheDir, or keep them in memory only if cacheDir is empty.
+func NewClientPool(site, cacheDir string) *ClientPool {
+	pool := &ClientPool{
+		site:     site,
+		cacheDir: cacheDir,
+		clients:  make(map[apiClientKey]*APIClient),
+		stats:    new(expvar.Map).Init(),
+	}
+	if site != "" {
+		apiCacheVars.Set(site, pool.stats)
+	}
+
+	return pool
+}
+
+// clientFor returns the client a source of the given type authenticates with, creating it on first use.
+// A source without its own token uses the provider's token variable: GITHUB_TOKEN, GITLAB_TOKEN or GITEA_TOKEN.
+func (pool *ClientPool) clientFor(config SourceConfig) (*APIClient, error) {
+	key := apiClientKey{
+		sourceType: config.Type,
+		baseURL:    strings.TrimSuffix(config.BaseURL, "/"),
+		token:      config.Token,
+		auth:       config.GitHubAuth,
+	}
+
+	name, tokenEnv := "GitHub API", "GITHUB_TOKEN"
+	switch key.sourceType {
+	case SourceGitHub, SourceGitHubArchive, "":
+		key.sourceType = SourceGitHub
+		if key.baseURL == "" {
+			key.baseURL = GitHubAPIURL
+		}
+	case SourceGitLab:
+		name, tokenEnv = "GitLab API", "GITLAB_TOKEN"
+		if key.baseURL == "" {
+			key.baseURL = GitLabAPIURL
+		}
+	case SourceGitea:
+		name, tokenEnv = "Gitea API", "GITEA_TOKEN"
+		if key.baseURL == "" {
+			return nil, fmt.Errorf("gitea source requires a base URL, e.g. https://gitea.example.com/api/v1")
+		}
+	default:
+		name, tokenEnv = key.baseURL, ""
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	if client, exists := pool.clients[key]; exists {
+		return client, nil
+	}
+
+	var auth TokenSource
+	switch {
+	case key.auth.AppID != 0:
+		appAuth, err := NewAppTokenSource(key.baseURL, key.auth)
+		if err != nil {
+			return nil, err
+		}
+		auth = appAuth
+	case key.token != "":
+		auth = StaticToken(key.token)
+	case tokenEnv != "" && os.Getenv(tokenEnv) != "":
+		auth = StaticToken(os.Getenv(tokenEnv))
+	case key.sourceType == SourceGitHub:
+		log.Println("Warning: GITHUB_TOKEN environment variable not set. API requests will be rate limited.")
+	}
+
+	client := NewAPIClient(name, key.baseURL, auth)
+	if key.sourceType == SourceGitLab || key.sourceType == SourceHTTP {
+		// GitLab accepts personal and project access tokens as bearer tokens, as do most web servers
+		client.authScheme = "Bearer"
+	}
+
+	// Keep the cached responses of different APIs and credentials apart, as they may see different content.
+	// github.com read with GITHUB_TOKEN is simply "github".
+	label := SourceGitHub
+	if key.sourceType != SourceGitHub || key.baseURL != GitHubAPIURL || key.token != "" || key.auth.AppID != 0 {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%s", key.baseURL, key.auth.AppID, key.auth.InstallationID, key.token)))
+		label = key.sourceType + "-" + hex.EncodeToString(sum[:6])
+	}
+
+	client.label = label
+	if pool.site != "" {
+		client.label = pool.site + "/" + label
+	}
+	client.cache.stats = pool.stats
+
+	if pool.cacheDir != "" {
+		dir := filepath.Join(pool.cacheDir, url.PathEscape(pool.site), label)
+		if err := client.SetCacheDir(dir); err != nil {
+			log.Printf("WARNING: not caching %s responses in %s: %v", name, dir, err)
+		}
+	}
+
+	pool.clients[key] = client
+	return client, nil
+}
+
+// ForgetRef drops the cached responses of requests for the given ref, such as a branch, tag or commit SHA
+// that is no longer read, e.g. the head commit of a closed pull request preview.
+func (gc *APIClient) ForgetRef(ref string) {
+	if ref == "" {
+		return
+	}
+
+	tarball := "/tarball/" + url.PathEscape(ref)
+	gc.cache.forget(func(u *url.URL) bool {
+		query := u.Query()
+		return query.Get("ref") == ref || query.Get("sha") == ref || query.Get("ref_name") == ref ||
+			strings.HasSuffix(u.EscapedPath(), tarball)
+	})
+}
+
+// URL returns the absolute URL of an API path, e.g. "/repos/owner/name/contents/".
+func (gc *APIClient) URL(path string) string {
+	return gc.baseURL + path
+}
+
+// RateLimit returns the quota the API reported in its most recent response.
+func (gc *APIClient) RateLimit() RateLimit {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	return gc.rateLimit
+}
+
+// Get sends a GET request for the URL with the given Accept header and returns the response if it succeeded.
+// Requests for previously fetched resources are conditional; if the API reports them unchanged, which does not count
+// against GitHub's rate limit, the cached body is returned as a 200 OK response.
+// Network errors and 5xx responses are retried with exponential backoff, and rate limited requests are retried
+// once the limit resets if that is within apiMaxRateLimitWait. Other failures return an *APIError.
+// The caller must close the response body.
+func (gc *APIClient) Get(ctx context.Context, url, accept string) (*http.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= apiMaxRetries; attempt++ {
+		// Don't spend a request that is known to be rejected
+		if wait := gc.exhaustedFor(); wait > 0 {
+			if wait > apiMaxRateLimitWait {
+				return nil, fmt.Errorf("%s rate limit exhausted until %s", gc.name, time.Now().Add(wait).Format(time.RFC3339))
+			}
+			log.Printf("%s rate limit exhausted, waiting %v", gc.name, wait.Round(time.Second))
+			if err := gc.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, wait, err := gc.do(ctx, url, accept)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if wait < 0 || attempt == apiMaxRetries {
+			break
+		}
+
+		if wait == 0 {
+			wait = apiRetryDelay * time.Duration(1<<attempt) // exponential backoff: 1s, 2s, 4s
+		}
+		if wait > apiMaxRateLimitWait {
+			return nil, fmt.Errorf("%w (retry after %v)", err, wait.Round(time.Second))
+		}
+
+		log.Printf("Attempt %d failed, retrying in %v: %v", attempt+1, wait, err)
+		if err := gc.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, lastErr
+}
+
+// do sends a single request. If it fails, wait is how long to wait before retrying it: zero to use the
+// default backoff, or negative if the request must not be retried.
+func (gc *APIClient) do(ctx context.Context, url, accept string) (resp *http.Response, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	req.Header.Set("Accept", accept)
+
+	key := cacheKey(url, accept)
+	cached := gc.cache.get(key)
+	if cached != nil {
+		cached.setConditionalHeaders(req)
+	}
+
+	if err := gc.authorize(ctx, req); err != nil {
+		return nil, 0, err
+	}
+
+	resp, err = gc.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	gc.updateRateLimit(resp.Header)
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		gc.cache.stats.Add("hits", 1)
+		return cached.response(resp), 0, nil
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err := gc.cache.storeResponse(key, resp); err != nil {
+			resp.Body.Close()
+			return nil, 0, err
+		}
+		return resp, 0, nil
+	}
+
+	// Read the error message so the connection can be reused
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	apiErr := &APIError{API: gc.name, StatusCode: resp.StatusCode, Message: apiErrorMessage(body)}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		// An installation token can be revoked before it expires; retry once with a new one
+		if app, ok := gc.auth.(*AppTokenSource); ok {
+			app.invalidate()
+			return nil, 0, apiErr
+		}
+		return nil, -1, apiErr
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if wait, limited := rateLimitWait(resp.Header, apiErr.Message); limited {
+			return nil, wait, apiErr
+		}
+		return nil, -1, apiErr
+	case resp.StatusCode >= 500:
+		return nil, 0, apiErr
+	default:
+		return nil, -1, apiErr
+	}
+}
+
+// authorize adds authentication to the request if a token is available, but never sends it to hosts other than
+// the API, e.g. archive mirrors.
+func (gc *APIClient) authorize(ctx context.Context, req *http.Request) error {
+	if gc.auth == nil || !strings.HasPrefix(req.URL.String(), gc.baseURL+"/") {
+		return nil
+	}
+
+	token, err := gc.auth.Token(ctx)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", gc.authScheme+" "+token)
+	}
+
+	return nil
+}
+
+// Post sends a POST request with the JSON encoding of body to the URL. Unlike Get it is sent once, as the requests
+// it is used for, such as setting a commit status, are cheap to send again with the next update.
+// A failed request returns an *APIError.
+func (gc *APIClient) Post(ctx context.Context, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := gc.authorize(ctx, req); err != nil {
+		return err
+	}
+
+	resp, err := gc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	gc.updateRateLimit(resp.Header)
+
+	// Read the response so the connection can be reused
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{API: gc.name, StatusCode: resp.StatusCode, Message: apiErrorMessage(respBody)}
+	}
+
+	return nil
+}
+
+// rateLimitWait determines whether a 403 or 429 response was caused by a rate limit and how long to wait before retrying.
+// Secondary rate limits either carry Retry-After or are only identified by their message.
+func rateLimitWait(header http.Header, message string) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if rateLimitHeader(header, "Remaining") == "0" {
+		if reset, err := strconv.ParseInt(rateLimitHeader(header, "Reset"), 10, 64); err == nil {
+			return max(time.Until(time.Unix(reset, 0)), time.Second), true
+		}
+		return apiSecondaryLimitWait, true
+	}
+
+	if strings.Contains(strings.ToLower(message), "rate limit") {
+		return apiSecondaryLimitWait, true
+	}
+
+	return 0, false
+}
+
+// rateLimitHeader returns the named rate limit header of a response, e.g. "Remaining" for X-RateLimit-Remaining
+// as sent by GitHub and Gitea, or RateLimit-Remaining as sent by GitLab.
+func rateLimitHeader(header http.Header, name string) string {
+	if value := header.Get("X-RateLimit-" + name); value != "" {
+		return value
+	}
+	return header.Get("RateLimit-" + name)
+}
+
+// apiErrorMessage extracts the message from an API error response body: GitHub, Gitea and most GitLab errors
+// carry a "message", GitLab's authentication errors an "error".
+func apiErrorMessage(body []byte) string {
+	var result struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+
+	if err := json.Unmarshal(body, &result); err != nil || result.Message == "" && result.Error == "" {
+		return strings.TrimSpace(string(body))
+	}
+
+	if result.Message == "" {
+		return result.Error
+	}
+	return result.Message
+}
+
+// updateRateLimit records the quota reported in the response headers and publishes it under the client's label.
+func (gc *APIClient) updateRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(rateLimitHeader(header, "Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(rateLimitHeader(header, "Limit"))
+	reset, _ := strconv.ParseInt(rateLimitHeader(header, "Reset"), 10, 64)
+
+	gc.mu.Lock()
+	gc.rateLimit = RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+	gc.mu.Unlock()
+
+	limitVar, remainingVar, resetVar := new(expvar.Int), new(expvar.Int), new(expvar.Int)
+	limitVar.Set(int64(limit))
+	remainingVar.Set(int64(remaining))
+	resetVar.Set(reset)
+	quota := new(expvar.Map).Init()
+	quota.Set("limit", limitVar)
+	quota.Set("remaining", remainingVar)
+	quota.Set("reset", resetVar)
+	apiRateLimitVars.Set(gc.label, quota)
+}
+
+// exhaustedFor returns how long until the quota resets if the last response reported none remaining.
+func (gc *APIClient) exhaustedFor() time.Duration {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+
+	if gc.rateLimit.Limit == 0 || gc.rateLimit.Remaining > 0 {
+		return 0
+	}
+
+	return time.Until(gc.rateLimit.Reset)
+}
+
+// sleepContext waits for the given duration, returning early with the context's error if it is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
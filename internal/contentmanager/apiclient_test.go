@@ -0,0 +1,386 @@
+package contentmanager
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testClient is an APIClient for a test server that records the waits between attempts instead of sleeping.
+type testClient struct {
+	*APIClient
+	mu       sync.Mutex
+	waits    []time.Duration
+	requests int
+}
+
+// newTestClient starts a test server answering the n-th request (counting from 0) with respond
+// and returns a client for it.
+func newTestClient(t *testing.T, respond func(w http.ResponseWriter, r *http.Request, n int)) *testClient {
+	t.Helper()
+
+	tc := &testClient{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc.mu.Lock()
+		n := tc.requests
+		tc.requests++
+		tc.mu.Unlock()
+
+		respond(w, r, n)
+	}))
+	t.Cleanup(server.Close)
+
+	tc.APIClient = NewAPIClient("test API", server.URL, nil)
+	tc.sleep = func(ctx context.Context, d time.Duration) error {
+		tc.mu.Lock()
+		defer tc.mu.Unlock()
+		tc.waits = append(tc.waits, d)
+		return nil
+	}
+
+	return tc
+}
+
+func (tc *testClient) get(t *testing.T) (string, error) {
+	t.Helper()
+
+	resp, err := tc.Get(context.Background(), tc.URL("/repos/owner/repo"), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body), nil
+}
+
+func TestGetRetriesServerErrors(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	body, err := tc.get(t)
+	if err != nil || body != "ok" {
+		t.Fatalf("Get() = %q, %v, want %q", body, err, "ok")
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second}
+	if !slices.Equal(tc.waits, want) {
+		t.Errorf("waited %v, want exponential backoff %v", tc.waits, want)
+	}
+}
+
+func TestGetRetryCap(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	_, err := tc.get(t)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Get() error = %v, want an APIError with status 500", err)
+	}
+	if tc.requests != apiMaxRetries+1 {
+		t.Errorf("sent %d requests, want %d", tc.requests, apiMaxRetries+1)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if !slices.Equal(tc.waits, want) {
+		t.Errorf("waited %v, want %v", tc.waits, want)
+	}
+}
+
+func TestGetDoesNotRetryClientErrors(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Not Found"}`))
+	})
+
+	_, err := tc.get(t)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Not Found" {
+		t.Fatalf("Get() error = %v, want an APIError with status 404", err)
+	}
+	if tc.requests != 1 {
+		t.Errorf("sent %d requests, want 1", tc.requests)
+	}
+}
+
+func TestGetRetryAfter(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	if body, err := tc.get(t); err != nil || body != "ok" {
+		t.Fatalf("Get() = %q, %v, want %q", body, err, "ok")
+	}
+
+	if want := []time.Duration{7 * time.Second}; !slices.Equal(tc.waits, want) {
+		t.Errorf("waited %v, want the Retry-After delay %v", tc.waits, want)
+	}
+}
+
+func TestGetSecondaryRateLimit(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	if body, err := tc.get(t); err != nil || body != "ok" {
+		t.Fatalf("Get() = %q, %v, want %q", body, err, "ok")
+	}
+
+	if want := []time.Duration{apiSecondaryLimitWait}; !slices.Equal(tc.waits, want) {
+		t.Errorf("waited %v, want %v", tc.waits, want)
+	}
+}
+
+func TestGetForbiddenWithoutRateLimit(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	})
+
+	if _, err := tc.get(t); err == nil {
+		t.Fatal("Get() succeeded, want an error")
+	}
+	if tc.requests != 1 || len(tc.waits) != 0 {
+		t.Errorf("sent %d requests and waited %v, want a single request", tc.requests, tc.waits)
+	}
+}
+
+func TestGetRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second)
+
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+		w.Write([]byte("ok"))
+	})
+
+	if body, err := tc.get(t); err != nil || body != "ok" {
+		t.Fatalf("Get() = %q, %v, want %q", body, err, "ok")
+	}
+
+	if len(tc.waits) == 0 || tc.waits[0] < 28*time.Second || tc.waits[0] > 31*time.Second {
+		t.Errorf("waited %v, want to wait about 30s for the rate limit to reset", tc.waits)
+	}
+	if limit := tc.RateLimit(); limit.Remaining != 4999 {
+		t.Errorf("RateLimit().Remaining = %d, want 4999", limit.Remaining)
+	}
+}
+
+func TestGetRateLimitResetTooFar(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	_, err := tc.get(t)
+	if err == nil || !strings.Contains(err.Error(), "retry after") {
+		t.Fatalf("Get() error = %v, want it to give up instead of waiting an hour", err)
+	}
+	if tc.requests != 1 || len(tc.waits) != 0 {
+		t.Errorf("sent %d requests and waited %v, want a single request", tc.requests, tc.waits)
+	}
+
+	// The exhausted quota is known, so the next request is not even sent
+	if _, err := tc.get(t); err == nil || !strings.Contains(err.Error(), "rate limit exhausted") {
+		t.Errorf("Get() error = %v, want the exhausted rate limit to be reported", err)
+	}
+	if tc.requests != 1 {
+		t.Errorf("sent %d requests, want no request while the quota is exhausted", tc.requests)
+	}
+}
+
+func TestGetConditionalRequest(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("cached"))
+	})
+
+	for i := range 2 {
+		body, err := tc.get(t)
+		if err != nil || body != "cached" {
+			t.Fatalf("Get() #%d = %q, %v, want %q", i+1, body, err, "cached")
+		}
+	}
+
+	if tc.requests != 2 {
+		t.Errorf("sent %d requests, want 2", tc.requests)
+	}
+}
+
+func TestGetGitLabRateLimitHeaders(t *testing.T) {
+	reset := time.Now().Add(20 * time.Second)
+
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		if n == 0 {
+			w.Header().Set("RateLimit-Limit", "2000")
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error": "Too Many Requests"}`))
+			return
+		}
+		w.Header().Set("RateLimit-Limit", "2000")
+		w.Header().Set("RateLimit-Remaining", "1999")
+		w.Write([]byte("ok"))
+	})
+
+	if body, err := tc.get(t); err != nil || body != "ok" {
+		t.Fatalf("Get() = %q, %v, want %q", body, err, "ok")
+	}
+
+	if len(tc.waits) == 0 || tc.waits[0] < 18*time.Second || tc.waits[0] > 21*time.Second {
+		t.Errorf("waited %v, want to wait about 20s for the rate limit to reset", tc.waits)
+	}
+	if limit := tc.RateLimit(); limit.Limit != 2000 || limit.Remaining != 1999 {
+		t.Errorf("RateLimit() = %+v, want 1999 of 2000 remaining", limit)
+	}
+}
+
+func TestAPIErrorNamesTheAPI(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": "invalid_token"}`))
+	})
+	tc.name = "GitLab API"
+
+	_, err := tc.get(t)
+	if err == nil || err.Error() != "GitLab API returned status 401: invalid_token" {
+		t.Errorf("Get() error = %v, want the GitLab API and its error message", err)
+	}
+}
+
+func TestSetCommitStatus(t *testing.T) {
+	var path, body string
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		data, _ := io.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(data)
+		if n > 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	source := NewGitHubSource(tc.APIClient, "owner", "repo")
+
+	status := CommitStatus{State: "success", TargetURL: "https://example.com/preview/1/", Context: "preview"}
+	if err := source.SetStatus(context.Background(), "abc123", status); err != nil {
+		t.Fatalf("SetStatus() error = %v", err)
+	}
+	if path != "POST /repos/owner/repo/statuses/abc123" {
+		t.Errorf("request = %s, want POST to the commit's statuses", path)
+	}
+	if want := `{"state":"success","target_url":"https://example.com/preview/1/","context":"preview"}`; body != want {
+		t.Errorf("body = %s, want %s", body, want)
+	}
+
+	// Status requests are not retried
+	var apiErr *APIError
+	if err := source.SetStatus(context.Background(), "abc123", status); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("SetStatus() error = %v, want a 404 APIError", err)
+	}
+	if tc.requests != 2 {
+		t.Errorf("requests = %d, want 2", tc.requests)
+	}
+}
+
+func TestGitHubSourceFetchLargeFile(t *testing.T) {
+	tc := newTestClient(t, func(w http.ResponseWriter, r *http.Request, n int) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/contents/small.md":
+			w.Write([]byte(`{"sha": "s1", "encoding": "base64", "content": "c21hbGw="}`))
+		case "/repos/owner/repo/contents/large.png":
+			w.Write([]byte(`{"sha": "l1", "encoding": "none", "content": ""}`))
+		case "/repos/owner/repo/git/blobs/l1":
+			w.Write([]byte(`{"sha": "l1", "encoding": "base64", "content": "bGFy\nZ2U="}`))
+		case "/repos/owner/repo/contents/huge.bin":
+			w.Write([]byte(`{"sha": "h1", "encoding": "none", "content": ""}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message": "too large"}`))
+		}
+	})
+	source := NewGitHubSource(tc.APIClient, "owner", "repo")
+
+	for path, want := range map[string]string{"small.md": "small", "large.png": "large"} {
+		if content, err := source.Fetch(context.Background(), path); err != nil || content != want {
+			t.Errorf("Fetch(%s) = %q, %v, want %q", path, content, err, want)
+		}
+	}
+
+	if content, err := source.Fetch(context.Background(), "huge.bin"); err == nil {
+		t.Errorf("Fetch(huge.bin) = %q, want an error rather than an empty file", content)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	pool := NewClientPool("docs", "")
+	first, err := pool.clientFor(SourceConfig{Type: SourceGitHub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, _ := pool.clientFor(SourceConfig{Type: SourceGitHubArchive})
+	gitlab, _ := pool.clientFor(SourceConfig{Type: SourceGitLab})
+
+	if first != archive {
+		t.Error("github and github-archive sources with the same credentials got different clients, want one shared client")
+	}
+	if first == gitlab {
+		t.Error("a gitlab source shares the GitHub client")
+	}
+	if first.label != "docs/github" || !strings.HasPrefix(gitlab.label, "docs/gitlab-") {
+		t.Errorf("labels = %q, %q, want them prefixed with the site", first.label, gitlab.label)
+	}
+
+	// Sites never share clients, so their quotas and cached responses stay apart
+	other, _ := NewClientPool("blog", "").clientFor(SourceConfig{Type: SourceGitHub})
+	if other == first || other.cache == first.cache {
+		t.Error("clients of different sites are shared, want a pool per site")
+	}
+}
@@ -0,0 +1,227 @@
+package contentmanager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxArchiveSize limits the uncompressed size of a repository archive, protecting against oversized downloads.
+const maxArchiveSize = 256 << 20
+
+// ArchiveSource reads Markdown files from a gzipped tarball of a repository, downloaded in a single request.
+// By default it uses the GitHub tarball endpoint, so a refresh costs one API call regardless of the number of files.
+type ArchiveSource struct {
+	sync.RWMutex
+	trackedRef
+	client    *APIClient
+	url       string
+	files     map[string]string // File contents keyed by path, relative to the root of the repository
+	repoOwner string            // Repository the archive is downloaded from, empty if the URL was configured
+	repoName  string
+	maxSize   int64 // Uncompressed size above which the archive is rejected
+}
+
+// NewArchiveSource initializes and returns a pointer to an ArchiveSource that downloads the tarball at the given URL.
+// The download is sent through the given client, e.g. one from the site's ClientPool.
+func NewArchiveSource(client *APIClient, url string) *ArchiveSource {
+	return &ArchiveSource{
+		client:  client,
+		url:     url,
+		maxSize: maxArchiveSize,
+	}
+}
+
+// githubArchiveURL returns the URL of the tarball of the default branch of a repository on the client's GitHub API.
+func githubArchiveURL(client *APIClient, repoOwner, repoName string) string {
+	return client.URL(fmt.Sprintf("/repos/%s/%s/tarball", repoOwner, repoName))
+}
+
+// Revision returns the SHA of the latest commit of the repository the archive is downloaded from, so an unchanged
+// repository is not downloaded again. Returns an empty string for archives downloaded from a configured URL.
+func (as *ArchiveSource) Revision(ctx context.Context) (string, error) {
+	if as.repoOwner == "" || as.repoName == "" {
+		return "", nil
+	}
+
+	return latestCommit(ctx, as.client, as.repoOwner, as.repoName, as.Ref())
+}
+
+// LatestRelease returns the tag of the latest published release of the repository the archive is downloaded from.
+// Returns an empty string for archives downloaded from a configured URL.
+func (as *ArchiveSource) LatestRelease(ctx context.Context) (string, error) {
+	if as.repoOwner == "" || as.repoName == "" {
+		return "", nil
+	}
+
+	return latestRelease(ctx, as.client, as.repoOwner, as.repoName)
+}
+
+// SetStatus sets the status of the commit with the given SHA in the repository the archive is downloaded from.
+// Does nothing for archives downloaded from a configured URL.
+func (as *ArchiveSource) SetStatus(ctx context.Context, sha string, status CommitStatus) error {
+	if as.repoOwner == "" || as.repoName == "" {
+		return nil
+	}
+
+	return setCommitStatus(ctx, as.client, as.repoOwner, as.repoName, sha, status)
+}
+
+// archiveURL returns the URL to download the archive from. Repository tarballs are downloaded at the ref the source
+// reads from; a configured URL is always used as is.
+func (as *ArchiveSource) archiveURL() string {
+	if ref := as.Ref(); ref != "" && as.repoOwner != "" && as.repoName != "" {
+		return as.url + "/" + url.PathEscape(ref)
+	}
+	return as.url
+}
+
+// String returns the URL the source downloads from.
+func (as *ArchiveSource) String() string {
+	return as.url
+}
+
+// List downloads and extracts the archive, then returns every file in the repository.
+func (as *ArchiveSource) List(ctx context.Context) ([]SourceFile, error) {
+	files, err := as.download(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	as.Lock()
+	as.files = files
+	as.Unlock()
+
+	return archiveEntries(files), nil
+}
+
+// Fetch returns the content of the file at the given path from the most recently downloaded archive.
+// The archive is downloaded first if List has not been called yet.
+func (as *ArchiveSource) Fetch(ctx context.Context, path string) (string, error) {
+	as.RLock()
+	files := as.files
+	as.RUnlock()
+
+	if files == nil {
+		if _, err := as.List(ctx); err != nil {
+			return "", err
+		}
+
+		as.RLock()
+		files = as.files
+		as.RUnlock()
+	}
+
+	content, exists := files[path]
+	if !exists {
+		return "", fmt.Errorf("file %s not found in archive", path)
+	}
+
+	return content, nil
+}
+
+// download fetches the archive and extracts its regular files into memory.
+func (as *ArchiveSource) download(ctx context.Context) (map[string]string, error) {
+	archiveURL := as.archiveURL()
+	log.Printf("downloading content archive from: %s", archiveURL)
+
+	resp, err := as.client.Get(ctx, archiveURL, "application/vnd.github.v3+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return extractTarball(resp.Body, as.maxSize)
+}
+
+// extractTarball reads a gzipped tarball and returns the contents of its regular files keyed by path.
+// The top-level directory that GitHub wraps repository archives in (e.g. "owner-repo-sha/") is stripped.
+// Returns an error if the uncompressed archive is larger than limit bytes.
+func extractTarball(r io.Reader, limit int64) (map[string]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	files := make(map[string]string)
+	tr := tar.NewReader(&sizeLimitReader{r: gz, limit: limit})
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Strip the top-level directory
+		_, path, found := strings.Cut(strings.TrimPrefix(header.Name, "./"), "/")
+		if !found || path == "" {
+			continue
+		}
+
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from archive: %w", path, err)
+		}
+
+		files[path] = string(content)
+	}
+
+	return files, nil
+}
+
+// sizeLimitReader reads from r and fails once more than limit bytes were read. Unlike io.LimitReader, which
+// reports a plain EOF, an oversized archive is never mistaken for a complete one.
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, fmt.Errorf("archive exceeds %d bytes", l.limit)
+	}
+	return n, err
+}
+
+// archiveEntries returns the files of the extracted archive, sorted by path.
+func archiveEntries(files map[string]string) []SourceFile {
+	entries := make([]SourceFile, 0, len(files))
+
+	for path, content := range files {
+		entries = append(entries, SourceFile{
+			Name: path[strings.LastIndex(path, "/")+1:],
+			Path: path,
+			Size: len(content),
+			SHA:  gitBlobSHA(content),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries
+}
+
+// forgetRef drops the client's cached responses for the given ref.
+func (as *ArchiveSource) forgetRef(ref string) {
+	as.client.ForgetRef(ref)
+}
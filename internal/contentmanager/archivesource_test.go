@@ -0,0 +1,176 @@
+package contentmanager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// tarball returns a gzipped tarball of the given files, wrapped in a top-level directory like GitHub archives.
+func tarball(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	if err := tw.WriteHeader(&tar.Header{Name: "owner-repo-abc123/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range files {
+		header := &tar.Header{Name: "owner-repo-abc123/" + path, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// archiveServer serves the archive at every path and records the paths requested.
+func archiveServer(t *testing.T, archive []byte) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		w.Header().Set("Content-Type", "application/x-gzip")
+		w.Write(archive)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requested
+}
+
+func TestArchiveSourceList(t *testing.T) {
+	files := map[string]string{
+		"README.md":           "# Readme",
+		"posts/hello.md":      "---\ntitle: Hello\n---\nHello",
+		"posts/2024/intro.md": "Intro",
+	}
+	server, _ := archiveServer(t, tarball(t, files))
+
+	source := NewArchiveSource(NewAPIClient("test API", server.URL, nil), server.URL+"/archive.tar.gz")
+	entries, err := source.List(context.Background())
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := []string{"README.md", "posts/2024/intro.md", "posts/hello.md"}
+	if len(entries) != len(want) {
+		t.Fatalf("List() returned %d files, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Path != want[i] {
+			t.Errorf("entries[%d].Path = %q, want %q", i, entry.Path, want[i])
+		}
+		if entry.SHA != gitBlobSHA(files[entry.Path]) {
+			t.Errorf("entries[%d].SHA = %q, want the blob SHA of its content", i, entry.SHA)
+		}
+	}
+	if entries[1].Name != "intro.md" {
+		t.Errorf("entries[1].Name = %q, want %q", entries[1].Name, "intro.md")
+	}
+}
+
+func TestArchiveSourceFetch(t *testing.T) {
+	server, requested := archiveServer(t, tarball(t, map[string]string{"posts/hello.md": "Hello"}))
+
+	source := NewArchiveSource(NewAPIClient("test API", server.URL, nil), server.URL+"/archive.tar.gz")
+
+	// Fetch downloads the archive if it has not been listed yet
+	content, err := source.Fetch(context.Background(), "posts/hello.md")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if content != "Hello" {
+		t.Errorf("Fetch() = %q, want %q", content, "Hello")
+	}
+
+	if _, err := source.Fetch(context.Background(), "posts/missing.md"); err == nil {
+		t.Error("Fetch() of a missing file succeeded, want an error")
+	}
+	if len(*requested) != 1 {
+		t.Errorf("archive downloaded %d times, want 1", len(*requested))
+	}
+}
+
+func TestArchiveSourceRef(t *testing.T) {
+	server, requested := archiveServer(t, tarball(t, map[string]string{"hello.md": "Hello"}))
+
+	client := NewAPIClient("test API", server.URL, nil)
+	source := NewArchiveSource(client, githubArchiveURL(client, "owner", "repo"))
+	source.repoOwner, source.repoName = "owner", "repo"
+	source.SetRef("release/v1")
+
+	if _, err := source.List(context.Background()); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := "/repos/owner/repo/tarball/release/v1"
+	if len(*requested) != 1 || (*requested)[0] != want {
+		t.Errorf("requested %v, want [%s]", *requested, want)
+	}
+}
+
+func TestExtractTarballStripsTopLevelDirectory(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, name := range []string{"./owner-repo-abc123/posts/hello.md", "top-level.md"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: 5})
+		tw.Write([]byte("Hello"))
+	}
+	tw.Close()
+	gz.Close()
+
+	files, err := extractTarball(&buf, maxArchiveSize)
+	if err != nil {
+		t.Fatalf("extractTarball() error = %v", err)
+	}
+
+	if len(files) != 1 || files["posts/hello.md"] != "Hello" {
+		t.Errorf("extractTarball() = %v, want only posts/hello.md", files)
+	}
+}
+
+func TestExtractTarballSizeLimit(t *testing.T) {
+	archive := tarball(t, map[string]string{"large.md": strings.Repeat("x", 64<<10)})
+
+	_, err := extractTarball(bytes.NewReader(archive), 32<<10)
+	if err == nil {
+		t.Fatal("extractTarball() of an oversized archive succeeded, want an error")
+	}
+	if !strings.Contains(err.Error(), "archive exceeds 32768 bytes") {
+		t.Errorf("extractTarball() error = %v, want it to report the size limit", err)
+	}
+
+	if _, err := extractTarball(bytes.NewReader(archive), 1<<20); err != nil {
+		t.Errorf("extractTarball() within the limit error = %v", err)
+	}
+}
+
+func TestArchiveSourceSizeLimit(t *testing.T) {
+	server, _ := archiveServer(t, tarball(t, map[string]string{"large.md": strings.Repeat("x", 64<<10)}))
+
+	source := NewArchiveSource(NewAPIClient("test API", server.URL, nil), server.URL+"/archive.tar.gz")
+	source.maxSize = 32 << 10
+
+	if _, err := source.List(context.Background()); err == nil || !strings.Contains(err.Error(), "archive exceeds") {
+		t.Errorf("List() error = %v, want the size limit to be reported", err)
+	}
+}
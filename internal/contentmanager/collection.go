@@ -0,0 +1,997 @@
+package contentmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CollectionConfig describes a named content collection, the source it is read from and where it is served.
+type CollectionConfig struct {
+	Name         string       // Unique collection name, e.g. "posts" or "cheatsheets"
+	Source       SourceConfig // Where the collection's Markdown files are read from
+	RoutePrefix  string       // URL prefix the collection is served under, e.g. "/posts"
+	SearchPath   string       // URL of the collection's search page, defaults to RoutePrefix + "/search"
+	Include      []string     // Globs of source paths parsed as documents, defaults to DefaultInclude
+	Exclude      []string     // Globs of source paths never parsed as documents, defaults to DefaultExclude
+	SlugFromPath bool         // Derive the slug from the file path when the front matter has none
+
+	Concurrency    int           // Number of files fetched in parallel during a refresh, defaults to DefaultConcurrency
+	RefreshTimeout time.Duration // Deadline for a whole refresh, defaults to DefaultRefreshTimeout
+	PollInterval   time.Duration // How often the source is checked for changes in the background, 0 to disable
+	FollowReleases bool          // Serve the content of the latest release instead of the default branch
+}
+
+// DefaultInclude matches Markdown files at any depth of a content source.
+var DefaultInclude = []string{"**/*.md"}
+
+// DefaultExclude skips repository housekeeping files that are not documents.
+var DefaultExclude = []string{"**/README.md", "**/LICENSE.md"}
+
+const (
+	// DefaultConcurrency is how many files a refresh fetches in parallel, enough to hide API latency
+	// without tripping GitHub's secondary rate limits.
+	DefaultConcurrency = 4
+
+	// DefaultRefreshTimeout bounds a refresh, so a slow or failing source cannot hold a webhook request open for minutes.
+	DefaultRefreshTimeout = 2 * time.Minute
+)
+
+// Collection manages the retrieval, storage, and filtering of the documents of a single content collection.
+type Collection struct {
+	sync.RWMutex
+	config     CollectionConfig
+	documents  map[string]Document  // Published documents keyed by slug
+	files      map[string]fileState // State of every document file keyed by source path
+	assets     map[string]bool      // Source paths of the other files, nil until the source has been listed
+	source     Source
+	lastReport *RefreshReport // Outcome of the most recent refresh
+	revision   string         // Source revision of the last complete refresh, empty if unknown
+	served     string         // Source revision the served content was read at, empty if unknown
+	guard      refreshGuard   // Keeps refreshes from running concurrently
+}
+
+// NewCollection initializes and returns a pointer to a new Collection for the given configuration, whose source
+// reads through a client from the given pool, or a client of its own if clients is nil.
+// Returns an error if the collection's source cannot be created.
+func NewCollection(config CollectionConfig, clients *ClientPool) (*Collection, error) {
+	source, err := NewSource(config.Source, clients)
+	if err != nil {
+		return nil, fmt.Errorf("%s collection: %w", config.Name, err)
+	}
+
+	if config.FollowReleases {
+		if config.Source.Ref != "" {
+			return nil, fmt.Errorf("%s collection: a ref cannot be set when following releases", config.Name)
+		}
+		_, refs := source.(RefSource)
+		_, releases := source.(ReleaseSource)
+		if !refs || !releases {
+			return nil, fmt.Errorf("%s collection: %s sources cannot follow releases", config.Name, config.Source.Type)
+		}
+	}
+
+	if config.SearchPath == "" {
+		config.SearchPath = config.RoutePrefix + "/search"
+	}
+
+	if len(config.Include) == 0 {
+		config.Include = DefaultInclude
+	}
+
+	if len(config.Exclude) == 0 {
+		config.Exclude = DefaultExclude
+	}
+
+	if config.Concurrency <= 0 {
+		config.Concurrency = DefaultConcurrency
+	}
+
+	if config.RefreshTimeout <= 0 {
+		config.RefreshTimeout = DefaultRefreshTimeout
+	}
+
+	return &Collection{
+		config:    config,
+		documents: make(map[string]Document),
+		files:     make(map[string]fileState),
+		source:    source,
+	}, nil
+}
+
+// Name returns the unique name of the collection.
+func (c *Collection) Name() string {
+	return c.config.Name
+}
+
+// Config returns the configuration the collection was created with.
+func (c *Collection) Config() CollectionConfig {
+	return c.config
+}
+
+// Source returns the source the collection reads its content from.
+func (c *Collection) Source() Source {
+	return c.source
+}
+
+// matchesAllTerms checks if all terms in the given list exist in the combined searchable fields of the provided document.
+func matchesAllTerms(doc Document, terms []string) bool {
+	searchText := strings.ToLower(strings.Join([]string{
+		doc.Title,
+		doc.Summary,
+		doc.RawContent,
+		strings.Join(doc.Tags, " "),
+	}, " "))
+
+	for _, term := range terms {
+		if !strings.Contains(searchText, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDocumentPath reports whether the file at the given source path should be parsed as a document,
+// i.e. it matches one of the collection's include globs and none of its exclude globs.
+func (c *Collection) isDocumentPath(path string) bool {
+	return matchAnyGlob(c.config.Include, path) && !matchAnyGlob(c.config.Exclude, path)
+}
+
+// IsContentPath reports whether a change to the file at the given source path affects the collection: the file is
+// a document, see isDocumentPath, or an asset of a per-post folder, see Asset.
+func (c *Collection) IsContentPath(file string) bool {
+	if c.isDocumentPath(file) {
+		return true
+	}
+
+	c.RLock()
+	defer c.RUnlock()
+
+	if c.assets[file] {
+		return true
+	}
+
+	// New assets belong to the closest folder of a document, like in Assets
+	for dir := path.Dir(file); dir != "."; dir = path.Dir(dir) {
+		if _, exists := c.files[dir+"/index.md"]; exists {
+			return isAssetName(strings.TrimPrefix(file, dir+"/"))
+		}
+	}
+
+	return false
+}
+
+// slugFromPath derives a slug from a document's path: "kubernetes/intro.md" becomes "intro",
+// and a per-post folder such as "2024/intro/index.md" becomes "intro".
+func slugFromPath(file string) string {
+	dir, name := path.Split(file)
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	if name == "index" && dir != "" {
+		return path.Base(dir)
+	}
+
+	return name
+}
+
+// categoryFromPath derives a category from the directory a document lives in: "kubernetes/intro.md" becomes
+// "kubernetes", and a per-post folder such as "2024/intro/index.md" becomes "2024". Root documents have no category.
+func categoryFromPath(file string) string {
+	dir := path.Dir(file)
+	if path.Base(file) == "index.md" {
+		dir = path.Dir(dir)
+	}
+
+	if dir == "." {
+		return ""
+	}
+
+	return dir
+}
+
+// isFolderDocument reports whether the document at the given path is the index.md of a per-post folder,
+// whose other files are the document's assets.
+func isFolderDocument(file string) bool {
+	return path.Base(file) == "index.md" && path.Dir(file) != "."
+}
+
+// fileState records what the collection knows about a document file in its source.
+type fileState struct {
+	SHA  string // Git blob SHA of the file content
+	Slug string // Slug of the published document parsed from the file, empty if it is not published
+}
+
+// loadDocument fetches and parses the Markdown file at the given path.
+// It returns the parsed document and the file's state, whose slug is only set if the document should be published,
+// i.e. it has a slug and is marked as published.
+func (c *Collection) loadDocument(ctx context.Context, path string) (Document, fileState, error) {
+	content, err := c.source.Fetch(ctx, path)
+	if err != nil {
+		return Document{}, fileState{}, fmt.Errorf("failed to fetch %s: %w", path, err)
+	}
+
+	state := fileState{SHA: gitBlobSHA(content)}
+
+	doc, err := parseMarkdown(content)
+	if err != nil {
+		return Document{}, state, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	doc.Path = path
+
+	// Fall back to the directory layout for the category and, if enabled, the slug
+	if doc.Category == "" {
+		doc.Category = categoryFromPath(path)
+	}
+
+	if doc.Slug == "" && c.config.SlugFromPath {
+		doc.Slug = slugFromPath(path)
+	}
+
+	// Check for empty slug
+	if doc.Slug == "" {
+		log.Printf("WARNING: Document '%s' in %s has empty slug, skipping", doc.Title, c.config.Name)
+		return doc, state, nil
+	}
+
+	// Relative links of a per-post folder point at the folder's assets, which are served below the document's URL
+	if isFolderDocument(path) {
+		if doc.Content, err = markdownToHtml([]byte(doc.RawContent), doc.Slug); err != nil {
+			return Document{}, state, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	// Only include published documents
+	if !doc.Published {
+		log.Printf("Skipping unpublished document: %s", doc.Title)
+		return doc, state, nil
+	}
+
+	state.Slug = doc.Slug
+	return doc, state, nil
+}
+
+// RefreshContent updates the collection by fetching and parsing Markdown files from its source.
+// It walks the whole source, processes valid, published documents and skips files excluded by the collection's globs.
+// Files whose SHA matches the previous refresh are not fetched again; their parsed documents are reused.
+// Changed files are fetched in parallel by up to Concurrency workers, and the refresh stops once RefreshTimeout
+// has passed or the context is cancelled.
+// A file that cannot be fetched or parsed does not stop the refresh: it is reported as failed and its previous
+// version, if any, keeps being served. An error is returned if the source cannot be listed, or, together with the
+// report of the files loaded so far, if the refresh was interrupted.
+// The method locks the collection for atomic updates and returns a report of every file it processed.
+// Refreshes of a collection never run concurrently; callers arriving while a refresh runs share the next one.
+func (c *Collection) RefreshContent(ctx context.Context) (*RefreshReport, error) {
+	return c.guard.full(ctx, func() (*RefreshReport, error) {
+		return c.refreshContent(ctx)
+	})
+}
+
+// refreshContent implements RefreshContent. The caller must hold the refresh guard.
+func (c *Collection) refreshContent(ctx context.Context) (*RefreshReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RefreshTimeout)
+	defer cancel()
+
+	report := newRefreshReport(c.config.Name)
+
+	// Collections following releases start from the latest release until a release event pins another one
+	if c.config.FollowReleases {
+		if source := c.source.(RefSource); source.Ref() == "" {
+			c.pinLatestRelease(ctx)
+		}
+	}
+
+	// Note the revision before listing, so changes pushed during the refresh are picked up by the next poll
+	revision := c.sourceRevision(ctx)
+
+	// List files in the content directory
+	files, err := c.source.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s content: %v", c.config.Name, err)
+	}
+
+	c.RLock()
+	oldDocuments, oldFiles := c.documents, c.files
+	c.RUnlock()
+
+	newDocuments := make(map[string]Document)
+	newFiles := make(map[string]fileState)
+	newAssets := make(map[string]bool)
+
+	// keepPrevious serves the previous version of a file, if there is one
+	keepPrevious := func(path string) bool {
+		previous, exists := oldFiles[path]
+		if !exists {
+			return false
+		}
+
+		doc, published := oldDocuments[previous.Slug]
+		if published && doc.Path == path && slugConflict(newDocuments, doc) == nil {
+			newDocuments[previous.Slug] = doc
+		} else {
+			previous.Slug = ""
+		}
+		newFiles[path] = previous
+		return true
+	}
+
+	log.Printf("Found %d files in %s source %s", len(files), c.config.Name, c.source)
+
+	var pending []string
+	for _, file := range files {
+		// Skip files that are not included or explicitly excluded
+		if !c.isDocumentPath(file.Path) {
+			log.Printf("Skipping non-document file: %s", file.Path)
+			newAssets[file.Path] = true
+			continue
+		}
+
+		// Reuse the previous result if the file has not changed since the last refresh
+		if previous, exists := oldFiles[file.Path]; exists && file.SHA != "" && previous.SHA == file.SHA {
+			keepPrevious(file.Path)
+			report.add(file.Path, FileUnchanged, time.Now())
+			continue
+		}
+
+		pending = append(pending, file.Path)
+	}
+
+	// Fetch and parse the changed Markdown files
+	for i, result := range c.loadDocuments(ctx, pending) {
+		path := pending[i]
+
+		if result.err != nil {
+			// Keep serving the last good version of the file until it is fixed
+			log.Printf("Failed to load %s, keeping previous version: %v", path, result.err)
+			report.fail(path, result.err, keepPrevious(path), result.start)
+			continue
+		}
+
+		if result.state.Slug != "" {
+			// Files are listed in order, so the document served first keeps the slug
+			if err := slugConflict(newDocuments, result.doc); err != nil {
+				log.Printf("Failed to load %s: %v", path, err)
+				report.fail(path, err, false, result.start)
+				continue
+			}
+			newDocuments[result.doc.Slug] = result.doc
+			newFiles[path] = result.state
+			report.add(path, FileUpdated, result.start)
+		} else {
+			newFiles[path] = result.state
+			report.add(path, FileSkipped, result.start)
+		}
+	}
+
+	// Report files that were served before but are no longer in the source
+	for path := range oldFiles {
+		if _, exists := newFiles[path]; !exists {
+			report.add(path, FileRemoved, time.Now())
+		}
+	}
+
+	report.finish()
+	log.Printf("Refreshed %s in %v: %d files fetched, %d unchanged, %d failed", c.config.Name, report.Duration.Round(time.Millisecond),
+		report.Count(FileUpdated)+report.Count(FileSkipped), report.Count(FileUnchanged), report.Count(FileFailed))
+
+	// Only a complete refresh is up to date with the revision, failed files must be retried by the next poll.
+	// The content is still served from the listed revision, with failed files at their previous version.
+	listed := revision
+	interrupted := ctx.Err()
+	if interrupted != nil || report.Count(FileFailed) > 0 {
+		revision = ""
+	}
+
+	report.Ref = c.Ref()
+	report.Revision = listed
+
+	// Update documents atomically
+	c.Lock()
+	c.documents = newDocuments
+	c.files = newFiles
+	c.assets = newAssets
+	c.lastReport = report
+	c.revision = revision
+	if listed != "" {
+		c.served = listed
+	}
+	c.Unlock()
+
+	if interrupted != nil {
+		return report, fmt.Errorf("refresh of %s interrupted: %w", c.config.Name, interrupted)
+	}
+
+	return report, nil
+}
+
+// Poll refreshes the collection if its source has changed since the last complete refresh. Sources that report
+// their revision, such as the SHA of the latest commit, are checked with a single cheap request; others are
+// refreshed, which only fetches files whose SHA changed. Collections following releases are pinned to a newer
+// release if one was published. Returns a nil report if the source has not changed.
+func (c *Collection) Poll(ctx context.Context) (*RefreshReport, error) {
+	if c.config.FollowReleases {
+		tag, err := c.source.(ReleaseSource).LatestRelease(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s for releases: %w", c.config.Name, err)
+		}
+
+		if tag != "" && tag != c.Ref() {
+			return c.PinRef(ctx, tag)
+		}
+	}
+
+	if versioned, ok := c.source.(Versioned); ok {
+		revision, err := versioned.Revision(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s for changes: %w", c.config.Name, err)
+		}
+
+		c.RLock()
+		current := c.revision
+		c.RUnlock()
+
+		if revision != "" && revision == current {
+			return nil, nil
+		}
+	}
+
+	return c.RefreshContent(ctx)
+}
+
+// PinRef switches the collection's source to the given ref, such as a release tag, and refreshes the collection
+// from it. It waits for any refresh in progress to finish. Returns an error if the source cannot read a ref.
+func (c *Collection) PinRef(ctx context.Context, ref string) (report *RefreshReport, err error) {
+	source, ok := c.source.(RefSource)
+	if !ok {
+		return nil, fmt.Errorf("%s source %s cannot be pinned to a ref", c.config.Name, c.source)
+	}
+
+	c.guard.exclusive(func() {
+		log.Printf("Pinning %s to %s", c.config.Name, ref)
+		previous := source.Ref()
+		source.SetRef(ref)
+		report, err = c.refreshContent(ctx)
+
+		// The responses cached for the previous ref, e.g. an older release, are not requested again
+		if cache, ok := c.source.(refCache); ok && err == nil && previous != ref {
+			cache.forgetRef(previous)
+		}
+	})
+
+	return report, err
+}
+
+// Discard drops the API responses cached for the ref the collection reads from. It is called once a collection
+// is no longer served, e.g. the content of a closed pull request preview, and must not be refreshed afterwards.
+func (c *Collection) Discard() {
+	if cache, ok := c.source.(refCache); ok {
+		cache.forgetRef(c.Ref())
+	}
+}
+
+// Ref returns the ref the collection's source reads from, or an empty string for the default branch
+// or sources without refs.
+func (c *Collection) Ref() string {
+	if source, ok := c.source.(RefSource); ok {
+		return source.Ref()
+	}
+	return ""
+}
+
+// pinLatestRelease points the source at the tag of the latest release, if there is one. The default branch
+// keeps being read if the releases cannot be looked up.
+func (c *Collection) pinLatestRelease(ctx context.Context) {
+	tag, err := c.source.(ReleaseSource).LatestRelease(ctx)
+	if err != nil {
+		log.Printf("Failed to look up the latest release of %s, reading the default branch: %v", c.config.Name, err)
+		return
+	}
+
+	if tag == "" {
+		log.Printf("No releases of %s yet, reading the default branch", c.config.Name)
+		return
+	}
+
+	log.Printf("Pinning %s to its latest release %s", c.config.Name, tag)
+	c.source.(RefSource).SetRef(tag)
+}
+
+// Revision returns the source revision the collection was last completely refreshed from, or an empty string
+// if it is unknown, e.g. because the source doesn't report revisions or the last refresh had failures.
+func (c *Collection) Revision() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.revision
+}
+
+// ServedRevision returns the source revision the served content was read at, such as a commit SHA, or an empty
+// string if it is unknown. Unlike Revision, it is kept when files fail to load, since the rest is still served.
+func (c *Collection) ServedRevision() string {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.served
+}
+
+// sourceRevision returns the current revision of the collection's source, or an empty string if the source
+// doesn't report revisions or the revision cannot be determined.
+func (c *Collection) sourceRevision(ctx context.Context) string {
+	versioned, ok := c.source.(Versioned)
+	if !ok {
+		return ""
+	}
+
+	revision, err := versioned.Revision(ctx)
+	if err != nil {
+		log.Printf("Failed to get the revision of %s source %s: %v", c.config.Name, c.source, err)
+		return ""
+	}
+
+	return revision
+}
+
+// ApplyChanges updates the collection from a list of changed and removed source paths, e.g. from a push webhook,
+// fetching only the changed files, in parallel. Removed files are dropped without listing the source. revision is
+// the commit the changes lead to, reported as the served revision; it may be empty if unknown.
+// Sources that serve files from a downloaded snapshot are refreshed in full instead, since the snapshot is stale.
+// Like RefreshContent, a changed file that cannot be loaded is reported as failed and keeps its previous version,
+// and an error is only returned if the refresh was interrupted. It waits for any refresh in progress to finish.
+func (c *Collection) ApplyChanges(ctx context.Context, changed, removed []string, revision string) (report *RefreshReport, err error) {
+	if _, ok := c.source.(*ArchiveSource); ok {
+		return c.RefreshContent(ctx)
+	}
+
+	c.guard.exclusive(func() {
+		report, err = c.applyChanges(ctx, changed, removed, revision)
+	})
+
+	return report, err
+}
+
+// applyChanges implements ApplyChanges. The caller must hold the refresh guard.
+func (c *Collection) applyChanges(ctx context.Context, changed, removed []string, revision string) (*RefreshReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.config.RefreshTimeout)
+	defer cancel()
+
+	report := newRefreshReport(c.config.Name)
+
+	for _, path := range removed {
+		if c.isDocumentPath(path) {
+			log.Printf("Removing %s file: %s", c.config.Name, path)
+			start := time.Now()
+			c.RemoveFile(path)
+			report.add(path, FileRemoved, start)
+		} else {
+			c.setAsset(path, false)
+		}
+	}
+
+	var pending []string
+	for _, path := range changed {
+		if c.isDocumentPath(path) {
+			pending = append(pending, path)
+		} else {
+			c.setAsset(path, true)
+		}
+	}
+
+	for i, result := range c.loadDocuments(ctx, pending) {
+		path := pending[i]
+
+		if result.err != nil {
+			c.RLock()
+			_, hasPrevious := c.files[path]
+			c.RUnlock()
+
+			log.Printf("Failed to update %s, keeping previous version: %v", path, result.err)
+			report.fail(path, result.err, hasPrevious, result.start)
+			continue
+		}
+
+		if err := c.storeDocument(path, result.doc, result.state); err != nil {
+			log.Printf("Failed to update %s, keeping previous version: %v", path, err)
+			report.fail(path, err, true, result.start)
+			continue
+		}
+		if result.state.Slug != "" {
+			report.add(path, FileUpdated, result.start)
+		} else {
+			report.add(path, FileSkipped, result.start)
+		}
+	}
+
+	report.finish()
+	report.Ref = c.Ref()
+	report.Revision = revision
+
+	// Whether every earlier change was applied is unknown, so the next poll refreshes the collection in full
+	c.Lock()
+	c.lastReport = report
+	c.revision = ""
+	if revision != "" {
+		c.served = revision
+	}
+	c.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return report, fmt.Errorf("update of %s interrupted: %w", c.config.Name, err)
+	}
+
+	return report, nil
+}
+
+// loadResult is the outcome of loading a single document file.
+type loadResult struct {
+	doc   Document
+	state fileState
+	err   error
+	start time.Time
+}
+
+// loadDocuments fetches and parses the document files at the given paths using up to Concurrency workers,
+// returning the results in the same order as the paths. Files not yet started when the context is done
+// fail with the context's error.
+func (c *Collection) loadDocuments(ctx context.Context, paths []string) []loadResult {
+	results := make([]loadResult, len(paths))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(c.config.Concurrency, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				log.Printf("Processing %s markdown file: %s", c.config.Name, paths[i])
+
+				start := time.Now()
+				doc, state, err := c.loadDocument(ctx, paths[i])
+				results[i] = loadResult{doc: doc, state: state, err: err, start: start}
+			}
+		}()
+	}
+
+dispatch:
+	for i := range paths {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			for ; i < len(paths); i++ {
+				results[i] = loadResult{err: ctx.Err(), start: time.Now()}
+			}
+			break dispatch
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// LastReport returns the report of the most recent refresh of the collection, or nil if it has not been refreshed.
+func (c *Collection) LastReport() *RefreshReport {
+	c.RLock()
+	defer c.RUnlock()
+
+	return c.lastReport
+}
+
+// RefreshFile re-parses a single Markdown file and updates, adds or removes the matching document.
+// Files that are not documents are ignored. It waits for any refresh in progress to finish.
+func (c *Collection) RefreshFile(ctx context.Context, path string) (err error) {
+	if !c.isDocumentPath(path) {
+		return nil
+	}
+
+	c.guard.exclusive(func() {
+		var doc Document
+		var state fileState
+		if doc, state, err = c.loadDocument(ctx, path); err == nil {
+			err = c.storeDocument(path, doc, state)
+		}
+	})
+
+	return err
+}
+
+// storeDocument replaces whatever was parsed from the file at the given path with a freshly loaded document.
+// Returns an error, keeping the previous version of the file, if another file's document already uses the slug.
+func (c *Collection) storeDocument(path string, doc Document, state fileState) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if state.Slug != "" {
+		if err := slugConflict(c.documents, doc); err != nil {
+			return err
+		}
+	}
+
+	c.removePathLocked(path)
+	c.files[path] = state
+	if state.Slug != "" {
+		c.documents[doc.Slug] = doc
+	}
+	return nil
+}
+
+// slugConflict returns an error if the document's slug is already used by a document parsed from another file.
+// Slugs derived from paths only use the file or folder name, so e.g. "2024/intro.md" and "2025/intro.md" collide.
+func slugConflict(documents map[string]Document, doc Document) error {
+	if existing, exists := documents[doc.Slug]; exists && existing.Path != doc.Path {
+		return fmt.Errorf("slug %q is already used by %s", doc.Slug, existing.Path)
+	}
+	return nil
+}
+
+// RemoveFile removes the document parsed from the Markdown file at the given path, if any.
+func (c *Collection) RemoveFile(path string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.removePathLocked(path)
+}
+
+// RemoveDir removes every document parsed from a Markdown file below the given directory.
+// Returns whether any document was removed.
+func (c *Collection) RemoveDir(dir string) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	removed := false
+	for path := range c.files {
+		if strings.HasPrefix(path, dir+"/") {
+			c.removePathLocked(path)
+			removed = true
+		}
+	}
+	for path := range c.assets {
+		if strings.HasPrefix(path, dir+"/") {
+			delete(c.assets, path)
+		}
+	}
+
+	return removed
+}
+
+// removePathLocked forgets the given file and drops the document parsed from it. The caller must hold the write lock.
+func (c *Collection) removePathLocked(path string) {
+	state, exists := c.files[path]
+	if !exists {
+		return
+	}
+
+	delete(c.files, path)
+	if doc, published := c.documents[state.Slug]; published && doc.Path == path {
+		delete(c.documents, state.Slug)
+	}
+}
+
+// setAsset records whether the file at the given path, which is not a document, exists in the source.
+func (c *Collection) setAsset(path string, exists bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.assets == nil {
+		return
+	}
+	if exists {
+		c.assets[path] = true
+	} else {
+		delete(c.assets, path)
+	}
+}
+
+// ErrAssetNotFound is returned by Asset if a document has no asset with the given name.
+var ErrAssetNotFound = errors.New("asset not found")
+
+// Asset returns the content of a file in the per-post folder of the document with the given slug, such as an image
+// the document links to: the asset "diagram.png" of "2024/intro/index.md" is read from "2024/intro/diagram.png".
+// Documents that are not the index.md of a folder have no assets, and Markdown and hidden files are never served.
+// Files the source did not list when the collection was last refreshed are not fetched, so requests for names that
+// don't exist cost no API requests. Returns ErrAssetNotFound if there is no such asset.
+func (c *Collection) Asset(ctx context.Context, slug, name string) (string, error) {
+	doc, exists := c.GetBySlug(slug)
+	if !exists || !isFolderDocument(doc.Path) {
+		return "", ErrAssetNotFound
+	}
+
+	// Rooting the name before cleaning it keeps ".." from leaving the folder
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if !isAssetName(name) {
+		return "", ErrAssetNotFound
+	}
+
+	file := path.Join(path.Dir(doc.Path), name)
+	c.RLock()
+	listed, known := c.assets[file], c.assets != nil
+	c.RUnlock()
+	if known && !listed {
+		return "", ErrAssetNotFound
+	}
+
+	content, err := c.source.Fetch(ctx, file)
+	if err != nil {
+		log.Printf("Failed to fetch %s asset %s: %v", c.config.Name, file, err)
+		return "", ErrAssetNotFound
+	}
+
+	return content, nil
+}
+
+// Assets lists the assets of every document read from a per-post folder, keyed by the document's slug.
+// Asset names are relative to the folder, like the names passed to Asset.
+func (c *Collection) Assets(ctx context.Context) (map[string][]string, error) {
+	folders := make(map[string]string) // Slugs keyed by folder
+	for _, doc := range c.GetAll() {
+		if isFolderDocument(doc.Path) {
+			folders[path.Dir(doc.Path)] = doc.Slug
+		}
+	}
+
+	if len(folders) == 0 {
+		return nil, nil
+	}
+
+	files, err := c.source.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s content: %v", c.config.Name, err)
+	}
+
+	assets := make(map[string][]string)
+	for _, file := range files {
+		// Assets may be nested, so look for the closest folder of a document
+		for dir := path.Dir(file.Path); dir != "."; dir = path.Dir(dir) {
+			slug, exists := folders[dir]
+			if !exists {
+				continue
+			}
+
+			if name := strings.TrimPrefix(file.Path, dir+"/"); isAssetName(name) {
+				assets[slug] = append(assets[slug], name)
+			}
+			break
+		}
+	}
+
+	return assets, nil
+}
+
+// isAssetName reports whether a path relative to a per-post folder names a file served as an asset,
+// i.e. it is neither a Markdown file nor hidden.
+func isAssetName(name string) bool {
+	if name == "" || path.Ext(name) == ".md" {
+		return false
+	}
+
+	for _, part := range strings.Split(name, "/") {
+		if isHiddenName(part) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetAll retrieves all documents, sorts them by date in descending order, and returns them as a slice. It is thread-safe.
+func (c *Collection) GetAll() []Document {
+	c.RLock()
+	defer c.RUnlock()
+
+	docs := make([]Document, 0, len(c.documents))
+	for _, doc := range c.documents {
+		docs = append(docs, doc)
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Date.After(docs[j].Date)
+	})
+
+	return docs
+}
+
+// GetByTag retrieves documents associated with a specific tag, sorted by date in descending order. It is thread-safe.
+func (c *Collection) GetByTag(tag string) []Document {
+	c.RLock()
+	defer c.RUnlock()
+
+	var tagged []Document
+
+	for _, doc := range c.documents {
+		for _, t := range doc.Tags {
+			if t == tag {
+				tagged = append(tagged, doc)
+				break
+			}
+		}
+	}
+
+	sort.Slice(tagged, func(i, j int) bool {
+		return tagged[i].Date.After(tagged[j].Date)
+	})
+
+	return tagged
+}
+
+// GetRecent retrieves the most recent `n` documents sorted by date in descending order. Returns all documents if fewer than `n` exist.
+func (c *Collection) GetRecent(n int) []Document {
+	docs := c.GetAll()
+	if len(docs) < n {
+		return docs
+	}
+
+	return docs[:n]
+}
+
+// GetOldest retrieves the oldest `n` documents sorted by date in ascending order. Returns all documents if fewer than `n` exist.
+func (c *Collection) GetOldest(n int) []Document {
+	docs := c.GetAll()
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Date.Before(docs[j].Date)
+	})
+
+	if len(docs) < n {
+		return docs
+	}
+
+	return docs[:n]
+}
+
+// Search filters documents by a given query string, returning all matches sorted by relevance and date in descending order.
+func (c *Collection) Search(query string) []Document {
+	c.RLock()
+	defer c.RUnlock()
+
+	if query == "" {
+		return []Document{}
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+	matches := make(map[string]Document)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Process documents concurrently
+	for slug, doc := range c.documents {
+		wg.Add(1)
+		go func(slug string, doc Document) {
+			defer wg.Done()
+
+			if matchesAllTerms(doc, terms) {
+				mu.Lock()
+				matches[slug] = doc
+				mu.Unlock()
+			}
+		}(slug, doc)
+	}
+	wg.Wait()
+
+	// Convert matches to slice
+	results := make([]Document, 0, len(matches))
+	for _, doc := range matches {
+		results = append(results, doc)
+	}
+
+	// Sort by relevance, using date for now
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Date.After(results[j].Date)
+	})
+
+	return results
+}
+
+// GetBySlug retrieves a document by its slug from the collection. Returns the document and a boolean indicating existence.
+func (c *Collection) GetBySlug(slug string) (Document, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	doc, exists := c.documents[slug]
+	return doc, exists
+}
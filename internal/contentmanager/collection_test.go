@@ -0,0 +1,220 @@
+package contentmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// localCollection writes the given files to a temporary directory and returns a refreshed collection reading from it.
+func localCollection(t *testing.T, files map[string]string) (*Collection, *RefreshReport) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for path, content := range files {
+		writeFile(t, filepath.Join(dir, filepath.FromSlash(path)), content)
+	}
+
+	collection, err := NewCollection(CollectionConfig{
+		Name:         "posts",
+		Source:       SourceConfig{Type: SourceLocal, Dir: dir},
+		RoutePrefix:  "/posts",
+		SlugFromPath: true,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := collection.RefreshContent(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshContent() error = %v", err)
+	}
+
+	return collection, report
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const publishedDocument = "---\ntitle: Intro\npublished: true\n---\n"
+
+func TestRefreshContentSlugCollision(t *testing.T) {
+	collection, report := localCollection(t, map[string]string{
+		"2024/intro.md": publishedDocument + "First",
+		"2025/intro.md": publishedDocument + "Second",
+	})
+
+	doc, exists := collection.GetBySlug("intro")
+	if !exists || doc.Path != "2024/intro.md" {
+		t.Fatalf("GetBySlug(intro) = %q, %v, want the document of 2024/intro.md", doc.Path, exists)
+	}
+
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Path != "2025/intro.md" || !strings.Contains(failed[0].Error, "2024/intro.md") {
+		t.Errorf("Failed() = %+v, want 2025/intro.md to fail because of the slug collision", failed)
+	}
+
+	// Removing the file that lost the slug must not remove the document that uses it
+	collection.RemoveFile("2025/intro.md")
+	if _, exists := collection.GetBySlug("intro"); !exists {
+		t.Error("RemoveFile() of the colliding file removed the document of another file")
+	}
+}
+
+func TestStoreDocumentSlugCollision(t *testing.T) {
+	collection, _ := localCollection(t, map[string]string{
+		"2024/intro.md": publishedDocument + "First",
+	})
+
+	writeFile(t, filepath.Join(collection.source.(*LocalSource).Dir(), "2025", "intro.md"), publishedDocument+"Second")
+
+	err := collection.RefreshFile(context.Background(), "2025/intro.md")
+	if err == nil || !strings.Contains(err.Error(), `slug "intro" is already used by 2024/intro.md`) {
+		t.Errorf("RefreshFile() error = %v, want a slug collision", err)
+	}
+
+	if doc, _ := collection.GetBySlug("intro"); doc.Path != "2024/intro.md" {
+		t.Errorf("GetBySlug(intro).Path = %q, want 2024/intro.md", doc.Path)
+	}
+}
+
+func TestFolderDocumentAssets(t *testing.T) {
+	collection, _ := localCollection(t, map[string]string{
+		"2024/intro/index.md":    publishedDocument + "![Diagram](diagram.png) [Notes](./notes/a.txt) [Home](/about) [Site](https://example.com)",
+		"2024/intro/diagram.png": "png",
+		"2024/intro/notes/a.txt": "notes",
+		"2024/intro/draft.md":    "draft",
+		"2024/intro/.secret":     "secret",
+		"other.md":               publishedDocument + "![Diagram](diagram.png)",
+	})
+
+	doc, exists := collection.GetBySlug("intro")
+	if !exists {
+		t.Fatal("GetBySlug(intro) found no document")
+	}
+
+	for _, want := range []string{`src="intro/diagram.png"`, `href="intro/notes/a.txt"`, `href="/about"`, `href="https://example.com"`} {
+		if !strings.Contains(doc.Content, want) {
+			t.Errorf("Content = %s, want it to contain %s", doc.Content, want)
+		}
+	}
+
+	// Documents outside of per-post folders keep their links
+	if other, _ := collection.GetBySlug("other"); !strings.Contains(other.Content, `src="diagram.png"`) {
+		t.Errorf("Content = %s, want relative links to be kept", other.Content)
+	}
+
+	ctx := context.Background()
+	if content, err := collection.Asset(ctx, "intro", "diagram.png"); err != nil || content != "png" {
+		t.Errorf("Asset(diagram.png) = %q, %v, want %q", content, err, "png")
+	}
+	if content, err := collection.Asset(ctx, "intro", "notes/a.txt"); err != nil || content != "notes" {
+		t.Errorf("Asset(notes/a.txt) = %q, %v, want %q", content, err, "notes")
+	}
+
+	for _, name := range []string{"draft.md", ".secret", "../../other.md", "missing.png"} {
+		if _, err := collection.Asset(ctx, "intro", name); !errors.Is(err, ErrAssetNotFound) {
+			t.Errorf("Asset(%s) error = %v, want ErrAssetNotFound", name, err)
+		}
+	}
+	if _, err := collection.Asset(ctx, "other", "diagram.png"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("Asset() of a document without a folder error = %v, want ErrAssetNotFound", err)
+	}
+
+	assets, err := collection.Assets(ctx)
+	if err != nil {
+		t.Fatalf("Assets() error = %v", err)
+	}
+	if got := strings.Join(assets["intro"], ","); got != "diagram.png,notes/a.txt" {
+		t.Errorf("Assets()[intro] = %s, want diagram.png,notes/a.txt", got)
+	}
+}
+
+// countingSource counts the files fetched from the source it wraps.
+type countingSource struct {
+	Source
+	fetched []string
+}
+
+func (s *countingSource) Fetch(ctx context.Context, path string) (string, error) {
+	s.fetched = append(s.fetched, path)
+	return s.Source.Fetch(ctx, path)
+}
+
+func TestAssetOnlyFetchesListedFiles(t *testing.T) {
+	collection, _ := localCollection(t, map[string]string{
+		"2024/intro/index.md":    publishedDocument,
+		"2024/intro/diagram.png": "png",
+	})
+	source := &countingSource{Source: collection.source}
+	collection.source = source
+
+	ctx := context.Background()
+	if _, err := collection.Asset(ctx, "intro", "missing.png"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("Asset(missing.png) error = %v, want ErrAssetNotFound", err)
+	}
+	if len(source.fetched) != 0 {
+		t.Errorf("fetched %v, want no request for a file the source did not list", source.fetched)
+	}
+
+	// Pushed files are served without waiting for a full refresh, removed ones are no longer fetched
+	writeFile(t, filepath.Join(collection.config.Source.Dir, "2024/intro/chart.png"), "chart")
+	if _, err := collection.ApplyChanges(ctx, []string{"2024/intro/chart.png"}, []string{"2024/intro/diagram.png"}, ""); err != nil {
+		t.Fatalf("ApplyChanges() error = %v", err)
+	}
+	if content, err := collection.Asset(ctx, "intro", "chart.png"); err != nil || content != "chart" {
+		t.Errorf("Asset(chart.png) = %q, %v, want %q", content, err, "chart")
+	}
+	if _, err := collection.Asset(ctx, "intro", "diagram.png"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("Asset(diagram.png) error = %v, want ErrAssetNotFound", err)
+	}
+	if got := strings.Join(source.fetched, ","); got != "2024/intro/chart.png" {
+		t.Errorf("fetched %s, want only 2024/intro/chart.png", got)
+	}
+
+	// Restoring a snapshot keeps the listed files
+	restored, err := NewCollection(collection.config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored.Restore(collection.Snapshot())
+	if _, err := restored.Asset(ctx, "intro", "diagram.png"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("Asset(diagram.png) of the restored collection error = %v, want ErrAssetNotFound", err)
+	}
+	if content, err := restored.Asset(ctx, "intro", "chart.png"); err != nil || content != "chart" {
+		t.Errorf("Asset(chart.png) of the restored collection = %q, %v, want %q", content, err, "chart")
+	}
+}
+
+func TestIsContentPath(t *testing.T) {
+	collection, _ := localCollection(t, map[string]string{
+		"2024/intro/index.md":    publishedDocument,
+		"2024/intro/diagram.png": "png",
+		"other.md":               publishedDocument,
+	})
+
+	for file, want := range map[string]bool{
+		"2025/new.md":               true,  // New documents match the include globs
+		"2024/intro/diagram.png":    true,  // Listed asset
+		"2024/intro/img/chart.png":  true,  // New asset of a per-post folder
+		"2024/intro/.hidden":        false, // Hidden files are never served
+		"logo.png":                  false, // Not in the folder of a document
+		"2024/other/photo.png":      false,
+		".github/workflows/ci.yaml": false,
+	} {
+		if got := collection.IsContentPath(file); got != want {
+			t.Errorf("IsContentPath(%s) = %v, want %v", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package contentmanager
+
+import (
+	"slices"
+	"testing"
+)
+
+// repoManager returns a ContentManager with a collection per repository, named after the repository.
+func repoManager(t *testing.T, repos ...[2]string) *ContentManager {
+	t.Helper()
+
+	cm := NewContentManager(nil)
+	for _, repo := range repos {
+		_, err := cm.Add(CollectionConfig{
+			Name:   repo[0] + "/" + repo[1],
+			Source: SourceConfig{Type: SourceGitHub, RepoOwner: repo[0], RepoName: repo[1]},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return cm
+}
+
+func names(collections []*Collection) []string {
+	var names []string
+	for _, collection := range collections {
+		names = append(names, collection.Name())
+	}
+	return names
+}
+
+func TestForRepoMatchesOwner(t *testing.T) {
+	cm := repoManager(t, [2]string{"jgndev", "posts"}, [2]string{"someone", "posts"})
+
+	tests := []struct {
+		repo string
+		want []string
+	}{
+		{"jgndev/posts", []string{"jgndev/posts"}},
+		{"JGNDev/Posts", []string{"jgndev/posts"}},
+		{"attacker/posts", nil},
+		{"posts", []string{"jgndev/posts", "someone/posts"}},
+	}
+
+	for _, tt := range tests {
+		if got := names(cm.ForRepo(tt.repo)); !slices.Equal(got, tt.want) {
+			t.Errorf("ForRepo(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestRenameRepo(t *testing.T) {
+	cm := repoManager(t, [2]string{"jgndev", "posts"})
+
+	if got := names(cm.RenameRepo("jgndev/posts", "jgndev/blog")); !slices.Equal(got, []string{"jgndev/posts"}) {
+		t.Errorf("RenameRepo() = %v, want the collection of the renamed repository", got)
+	}
+	if got := names(cm.RenameRepo("jgndev/blog", "jgndev/writing")); !slices.Equal(got, []string{"jgndev/posts"}) {
+		t.Errorf("RenameRepo() after an earlier rename = %v, want the collection of the renamed repository", got)
+	}
+	if got := cm.ForRepo("other/writing"); len(got) != 0 {
+		t.Errorf("ForRepo() of another owner's repository with the new name = %v, want none", names(got))
+	}
+}
@@ -0,0 +1,20 @@
+package contentmanager
+
+import "time"
+
+// Document represents a single Markdown entry in a content collection, including its metadata and rendered content.
+type Document struct {
+	ID          string    `yaml:"ID"`
+	Date        time.Time `yaml:"date"`
+	DisplayDate string
+	Title       string `yaml:"title"`
+	Author      string `yaml:"author"`
+	Summary     string `yaml:"summary"`
+	Content     string
+	RawContent  string
+	Slug        string   `yaml:"slug"`
+	Category    string   `yaml:"category"`
+	Tags        []string `yaml:"tags"`
+	Published   bool     `yaml:"published"`
+	Path        string   // Path of the Markdown file in the collection's source
+}
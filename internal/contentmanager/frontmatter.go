@@ -10,18 +10,7 @@ type FrontMatter struct {
 	Author    string    `yaml:"author"`
 	Summary   string    `yaml:"summary"`
 	Slug      string    `yaml:"slug"`
-	Tags      []string  `yaml:"tags"`
-	Published bool      `yaml:"published"`
-}
-
-// CheatsheetFrontMatter represents metadata for a cheatsheet, typically extracted from its frontmatter in YAML format.
-type CheatsheetFrontMatter struct {
-	ID        string    `yaml:"id"`
-	Date      time.Time `yaml:"date"`
-	Title     string    `yaml:"title"`
-	Author    string    `yaml:"author"`
-	Summary   string    `yaml:"summary"`
-	Slug      string    `yaml:"slug"`
+	Category  string    `yaml:"category"`
 	Tags      []string  `yaml:"tags"`
 	Published bool      `yaml:"published"`
 }
@@ -0,0 +1,220 @@
+package contentmanager
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// githubAppJWTLifetime is how long the JWT authenticating as the app is valid; GitHub allows at most 10 minutes.
+	githubAppJWTLifetime = 9 * time.Minute
+
+	// githubAppClockSkew backdates the JWT to tolerate clocks running ahead of GitHub's.
+	githubAppClockSkew = time.Minute
+
+	// githubTokenRefreshMargin is how long before expiry an installation token is replaced,
+	// so a token never expires halfway through a refresh.
+	githubTokenRefreshMargin = 5 * time.Minute
+)
+
+// TokenSource provides the token an APIClient authenticates its requests with.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource for a long-lived token such as a personal access token.
+type StaticToken string
+
+// Token returns the token itself.
+func (t StaticToken) Token(ctx context.Context) (string, error) {
+	return string(t), nil
+}
+
+// GitHubAuthConfig describes how a GitHub-backed source authenticates. If AppID is set, the source authenticates as
+// an installation of that GitHub App; otherwise it uses the personal access token in GITHUB_TOKEN.
+type GitHubAuthConfig struct {
+	AppID          int64  // ID of the GitHub App
+	InstallationID int64  // ID of the app's installation on the account owning the content repository
+	PrivateKey     string // PEM-encoded private key of the app, e.g. from a secret
+	PrivateKeyPath string // File holding the PEM-encoded private key, used if PrivateKey is empty
+}
+
+// AppTokenSource is a TokenSource for GitHub App installation tokens. It signs a JWT with the app's private key,
+// exchanges it for a short-lived installation token, and caches that token until shortly before it expires.
+type AppTokenSource struct {
+	client         *http.Client
+	baseURL        string
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAppTokenSource initializes and returns a pointer to an AppTokenSource requesting installation tokens from the API
+// at baseURL. Returns an error if the configuration is incomplete or the private key cannot be read.
+func NewAppTokenSource(baseURL string, config GitHubAuthConfig) (*AppTokenSource, error) {
+	if config.AppID == 0 || config.InstallationID == 0 {
+		return nil, fmt.Errorf("github app authentication requires an app ID and an installation ID")
+	}
+
+	pemData := []byte(config.PrivateKey)
+	if len(pemData) == 0 {
+		if config.PrivateKeyPath == "" {
+			return nil, fmt.Errorf("github app authentication requires a private key")
+		}
+
+		var err error
+		if pemData, err = os.ReadFile(config.PrivateKeyPath); err != nil {
+			return nil, fmt.Errorf("failed to read github app private key: %w", err)
+		}
+	}
+
+	key, err := parseRSAPrivateKey(pemData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid github app private key: %w", err)
+	}
+
+	return &AppTokenSource{
+		client:         &http.Client{Timeout: 30 * time.Second},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		appID:          config.AppID,
+		installationID: config.InstallationID,
+		key:            key,
+	}, nil
+}
+
+// Token returns the cached installation token, requesting a new one if it is missing or about to expire.
+func (ts *AppTokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.token != "" && time.Until(ts.expires) > githubTokenRefreshMargin {
+		return ts.token, nil
+	}
+
+	token, expires, err := ts.requestToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get installation token for github app %d: %w", ts.appID, err)
+	}
+
+	ts.token, ts.expires = token, expires
+	return token, nil
+}
+
+// invalidate discards the cached installation token, e.g. after GitHub rejected it because the installation changed.
+func (ts *AppTokenSource) invalidate() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.token = ""
+}
+
+// requestToken exchanges a freshly signed app JWT for an installation token.
+func (ts *AppTokenSource) requestToken(ctx context.Context) (string, time.Time, error) {
+	jwt, err := ts.signJWT(time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", ts.baseURL, ts.installationID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", time.Time{}, &APIError{API: "GitHub API", StatusCode: resp.StatusCode, Message: apiErrorMessage(body)}
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", time.Time{}, err
+	}
+
+	if result.Token == "" {
+		return "", time.Time{}, fmt.Errorf("no token in response")
+	}
+
+	return result.Token, result.ExpiresAt, nil
+}
+
+// signJWT returns an RS256 JWT identifying the app, as required to request installation tokens.
+func (ts *AppTokenSource) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-githubAppClockSkew).Unix(),
+		"exp": now.Add(githubAppJWTLifetime).Unix(),
+		"iss": ts.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, ts.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseRSAPrivateKey decodes a PEM-encoded RSA private key in PKCS #1 form, as downloaded from GitHub, or PKCS #8 form.
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+
+	return rsaKey, nil
+}
@@ -0,0 +1,210 @@
+package contentmanager
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenServer is a fake GitHub installation token endpoint that verifies the app JWT of every request.
+type tokenServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	issued   int
+	lifetime time.Duration // How long issued tokens are valid
+	status   int           // Status to respond with instead of issuing a token, 0 to issue tokens
+}
+
+func newTokenServer(t *testing.T, key *rsa.PrivateKey, appID, installationID int64) *tokenServer {
+	t.Helper()
+
+	ts := &tokenServer{lifetime: time.Hour}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != fmt.Sprintf("/app/installations/%d/access_tokens", installationID) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := verifyAppJWT(&key.PublicKey, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), appID); err != nil {
+			t.Errorf("invalid app JWT: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+
+		if ts.status != 0 {
+			w.WriteHeader(ts.status)
+			w.Write([]byte(`{"message": "Bad credentials"}`))
+			return
+		}
+
+		ts.issued++
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{
+			"token":      fmt.Sprintf("token-%d", ts.issued),
+			"expires_at": time.Now().Add(ts.lifetime).UTC().Format(time.RFC3339),
+		})
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// verifyAppJWT checks the signature and claims of a JWT signed by AppTokenSource.
+func verifyAppJWT(key *rsa.PublicKey, jwt string, appID int64) error {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return errors.New("not a JWT")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return err
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(parts[0]); err != nil || json.Unmarshal(data, &header) != nil || header.Alg != "RS256" {
+		return errors.New("header does not declare RS256")
+	}
+
+	var claims struct {
+		IAT int64 `json:"iat"`
+		EXP int64 `json:"exp"`
+		ISS int64 `json:"iss"`
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case claims.ISS != appID:
+		return fmt.Errorf("iss = %d, want %d", claims.ISS, appID)
+	case claims.IAT > now:
+		return errors.New("issued in the future")
+	case claims.EXP <= now || claims.EXP-claims.IAT > int64((10*time.Minute).Seconds()):
+		return errors.New("expiry is in the past or more than 10 minutes after issue")
+	}
+
+	return nil
+}
+
+// appTokenSource returns an AppTokenSource for a freshly generated key and a fake token endpoint.
+func appTokenSource(t *testing.T) (*AppTokenSource, *tokenServer) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTokenServer(t, key, 42, 7)
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	source, err := NewAppTokenSource(server.URL, GitHubAuthConfig{AppID: 42, InstallationID: 7, PrivateKey: string(pemKey)})
+	if err != nil {
+		t.Fatalf("NewAppTokenSource() error = %v", err)
+	}
+
+	return source, server
+}
+
+func TestAppTokenSourceCachesToken(t *testing.T) {
+	source, server := appTokenSource(t)
+
+	for range 3 {
+		token, err := source.Token(context.Background())
+		if err != nil || token != "token-1" {
+			t.Fatalf("Token() = %q, %v, want %q", token, err, "token-1")
+		}
+	}
+
+	if server.issued != 1 {
+		t.Errorf("requested %d installation tokens, want 1", server.issued)
+	}
+}
+
+func TestAppTokenSourceRefreshesBeforeExpiry(t *testing.T) {
+	source, server := appTokenSource(t)
+
+	// Tokens expiring within the refresh margin are replaced on every use
+	server.lifetime = githubTokenRefreshMargin - time.Minute
+
+	first, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	second, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	if first == second || server.issued != 2 {
+		t.Errorf("Token() = %q then %q after %d requests, want a new token for the expiring one", first, second, server.issued)
+	}
+}
+
+func TestAppTokenSourceInvalidate(t *testing.T) {
+	source, server := appTokenSource(t)
+
+	source.Token(context.Background())
+	source.invalidate()
+
+	if token, err := source.Token(context.Background()); err != nil || token != "token-2" {
+		t.Errorf("Token() after invalidate = %q, %v, want a new token", token, err)
+	}
+	if server.issued != 2 {
+		t.Errorf("requested %d installation tokens, want 2", server.issued)
+	}
+}
+
+func TestAppTokenSourceError(t *testing.T) {
+	source, server := appTokenSource(t)
+	server.status = http.StatusUnauthorized
+
+	_, err := source.Token(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Bad credentials" {
+		t.Errorf("Token() error = %v, want an APIError with status 401", err)
+	}
+}
+
+func TestNewAppTokenSourceRequiresCompleteConfig(t *testing.T) {
+	for _, config := range []GitHubAuthConfig{
+		{AppID: 42, PrivateKey: "key"},
+		{InstallationID: 7, PrivateKey: "key"},
+		{AppID: 42, InstallationID: 7},
+		{AppID: 42, InstallationID: 7, PrivateKey: "not a PEM key"},
+	} {
+		if _, err := NewAppTokenSource(GitHubAPIURL, config); err == nil {
+			t.Errorf("NewAppTokenSource(%+v) succeeded, want an error", config)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package contentmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// githubContent represents a content item in a GitHub repository, which may be a file or a directory.
+type githubContent struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Size int    `json:"size"`
+	SHA  string `json:"sha"`
+}
+
+// GitHubSource reads Markdown files from a GitHub repository using the GitHub Contents API.
+// The repository is read at its default branch unless another ref is set.
+type GitHubSource struct {
+	trackedRef
+	client    *APIClient
+	repoOwner string
+	repoName  string
+}
+
+// NewGitHubSource initializes and returns a pointer to a GitHubSource for the given repository owner and name.
+// Requests are sent through the given client, e.g. one from the site's ClientPool.
+func NewGitHubSource(client *APIClient, repoOwner, repoName string) *GitHubSource {
+	return &GitHubSource{
+		client:    client,
+		repoOwner: repoOwner,
+		repoName:  repoName,
+	}
+}
+
+// String returns the repository the source reads from.
+func (gs *GitHubSource) String() string {
+	if gs.client.baseURL != GitHubAPIURL {
+		return fmt.Sprintf("%s/repos/%s/%s", gs.client.baseURL, gs.repoOwner, gs.repoName)
+	}
+	return fmt.Sprintf("github.com/%s/%s", gs.repoOwner, gs.repoName)
+}
+
+// List retrieves every file in the repository, walking into subdirectories.
+func (gs *GitHubSource) List(ctx context.Context) ([]SourceFile, error) {
+	var files []SourceFile
+	if err := gs.walk(ctx, "", &files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// walk lists the directory at the given path and appends its files to files, descending into subdirectories.
+func (gs *GitHubSource) walk(ctx context.Context, path string, files *[]SourceFile) error {
+	contents, err := gs.listRepoContent(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		switch content.Type {
+		case "file":
+			*files = append(*files, SourceFile{
+				Name: content.Name,
+				Path: content.Path,
+				Size: content.Size,
+				SHA:  content.SHA,
+			})
+		case "dir":
+			if err := gs.walk(ctx, content.Path, files); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Revision returns the SHA of the latest commit at the ref the repository is read from.
+func (gs *GitHubSource) Revision(ctx context.Context) (string, error) {
+	return latestCommit(ctx, gs.client, gs.repoOwner, gs.repoName, gs.Ref())
+}
+
+// LatestRelease returns the tag of the latest published release of the repository, or an empty string if there is none.
+func (gs *GitHubSource) LatestRelease(ctx context.Context) (string, error) {
+	return latestRelease(ctx, gs.client, gs.repoOwner, gs.repoName)
+}
+
+// SetStatus sets the status of the commit with the given SHA in the repository.
+func (gs *GitHubSource) SetStatus(ctx context.Context, sha string, status CommitStatus) error {
+	return setCommitStatus(ctx, gs.client, gs.repoOwner, gs.repoName, sha, status)
+}
+
+// setCommitStatus sets the status of the commit with the given SHA of a repository. GitHub and Gitea share the
+// statuses endpoint.
+func setCommitStatus(ctx context.Context, client *APIClient, repoOwner, repoName, sha string, status CommitStatus) error {
+	if description := []rune(status.Description); len(description) > 140 {
+		status.Description = string(description[:139]) + "…"
+	}
+
+	statusURL := client.URL(fmt.Sprintf("/repos/%s/%s/statuses/%s", repoOwner, repoName, url.PathEscape(sha)))
+	if err := client.Post(ctx, statusURL, status); err != nil {
+		return fmt.Errorf("failed to set the status of %s in %s/%s: %w", sha, repoOwner, repoName, err)
+	}
+
+	return nil
+}
+
+// latestCommit returns the SHA of the latest commit at the given ref of a repository, or on its default branch if
+// ref is empty. Returns an empty string if the repository has no commits. GitHub and Gitea share the commits
+// endpoint but page it with per_page and limit respectively, so both are sent. Unchanged repositories are answered
+// from the client's cache with a conditional request, which doesn't count against GitHub's rate limit.
+func latestCommit(ctx context.Context, client *APIClient, repoOwner, repoName, ref string) (string, error) {
+	commitsURL := client.URL(fmt.Sprintf("/repos/%s/%s/commits?per_page=1&limit=1", repoOwner, repoName))
+	if ref != "" {
+		commitsURL += "&sha=" + url.QueryEscape(ref)
+	}
+
+	resp, err := client.Get(ctx, commitsURL, "application/vnd.github.v3+json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var commits []struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return "", fmt.Errorf("failed to decode commits of %s/%s: %w", repoOwner, repoName, err)
+	}
+
+	if len(commits) == 0 {
+		return "", nil
+	}
+
+	return commits[0].SHA, nil
+}
+
+// latestRelease returns the tag of the latest published release of a repository on GitHub or Gitea,
+// or an empty string if the repository has no releases.
+func latestRelease(ctx context.Context, client *APIClient, repoOwner, repoName string) (string, error) {
+	resp, err := client.Get(ctx, client.URL(fmt.Sprintf("/repos/%s/%s/releases/latest", repoOwner, repoName)), "application/vnd.github.v3+json")
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return "", nil
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to decode latest release of %s/%s: %w", repoOwner, repoName, err)
+	}
+
+	return release.TagName, nil
+}
+
+// Fetch retrieves the content of the file at the given path in the repository.
+func (gs *GitHubSource) Fetch(ctx context.Context, path string) (string, error) {
+	return gs.fetchFileContent(ctx, path)
+}
+
+// contentsURL returns the Contents API URL of the given path in the repository, at the ref the source reads from.
+func (gs *GitHubSource) contentsURL(path string) string {
+	contentsURL := gs.client.URL(fmt.Sprintf("/repos/%s/%s/contents/%s", gs.repoOwner, gs.repoName, path))
+	if ref := gs.Ref(); ref != "" {
+		contentsURL += "?ref=" + url.QueryEscape(ref)
+	}
+	return contentsURL
+}
+
+// listRepoContent retrieves the content of the GitHub repository for the provided path.
+// It supports retrieving directories or single files and returns an array of githubContent items.
+func (gs *GitHubSource) listRepoContent(ctx context.Context, path string) ([]githubContent, error) {
+	url := gs.contentsURL(path)
+	log.Printf("fetching content from: %s", url)
+
+	resp, err := gs.client.Get(ctx, url, "application/vnd.github.v3+json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode contents of %s: %w", path, err)
+	}
+
+	// Try to decode as an array first (directory listing)
+	var contents []githubContent
+	if err := json.Unmarshal(raw, &contents); err != nil {
+		// If that fails, it might be a single file
+		var singleContent githubContent
+		if err := json.Unmarshal(raw, &singleContent); err != nil {
+			return nil, fmt.Errorf("failed to decode response as array or single file: %v", err)
+		}
+		contents = []githubContent{singleContent}
+	}
+
+	return contents, nil
+}
+
+// fetchFileContent retrieves the content of a file from the GitHub repository by its path.
+// The Contents API only includes the content of files up to 1 MB; larger ones, up to GitHub's limit of 100 MB, come
+// with an encoding of "none" and are fetched from the Git blobs API by their SHA instead.
+func (gs *GitHubSource) fetchFileContent(ctx context.Context, path string) (string, error) {
+	resp, err := gs.client.Get(ctx, gs.contentsURL(path), "application/vnd.github.v3+json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result githubBlob
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	if (result.Encoding == "none" || result.Encoding == "") && result.SHA != "" {
+		log.Printf("%s is too large for the contents API, fetching blob %s", path, result.SHA)
+		return gs.fetchBlob(ctx, path, result.SHA)
+	}
+
+	return result.decode(path)
+}
+
+// fetchBlob retrieves the content of the file at path by the SHA of its Git blob.
+func (gs *GitHubSource) fetchBlob(ctx context.Context, path, sha string) (string, error) {
+	blobURL := gs.client.URL(fmt.Sprintf("/repos/%s/%s/git/blobs/%s", gs.repoOwner, gs.repoName, sha))
+	resp, err := gs.client.Get(ctx, blobURL, "application/vnd.github.v3+json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var blob githubBlob
+	if err := json.NewDecoder(resp.Body).Decode(&blob); err != nil {
+		return "", fmt.Errorf("failed to decode blob of %s: %w", path, err)
+	}
+
+	return blob.decode(path)
+}
+
+// githubBlob is a file as returned by the Contents API, or a blob as returned by the Git blobs API.
+type githubBlob struct {
+	SHA      string `json:"sha"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding"`
+}
+
+// decode returns the blob's content. An encoding other than base64 or utf-8, such as the "none" of files too large
+// to be included, is an error rather than an empty file.
+func (b githubBlob) decode(path string) (string, error) {
+	switch b.Encoding {
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(b.Content)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode content of %s: %w", path, err)
+		}
+		return string(decoded), nil
+	case "utf-8":
+		return b.Content, nil
+	default:
+		return "", fmt.Errorf("content of %s not included in the response (encoding %q)", path, b.Encoding)
+	}
+}
+
+// forgetRef drops the client's cached responses for the given ref.
+func (gs *GitHubSource) forgetRef(ref string) {
+	gs.client.ForgetRef(ref)
+}
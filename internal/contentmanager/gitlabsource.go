@@ -0,0 +1,165 @@
+package contentmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+)
+
+// GitLabAPIURL is the base URL of the GitLab.com REST API.
+const GitLabAPIURL = "https://gitlab.com/api/v4"
+
+// gitlabTreeEntry represents an item of a GitLab repository tree, which may be a file ("blob") or a directory ("tree").
+type gitlabTreeEntry struct {
+	ID   string `json:"id"` // Git object SHA
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// GitLabSource reads Markdown files from a GitLab project using the repository tree and files APIs,
+// on GitLab.com or a self-hosted instance. The project is read at its default branch unless another ref is set.
+type GitLabSource struct {
+	trackedRef
+	client  *APIClient
+	project string // Full path of the project, e.g. "group/posts"
+}
+
+// NewGitLabSource initializes and returns a pointer to a GitLabSource for the project with the given full path.
+// Requests are sent through the given client, which must point at a GitLab API.
+func NewGitLabSource(client *APIClient, project string) *GitLabSource {
+	return &GitLabSource{
+		client:  client,
+		project: project,
+	}
+}
+
+// String returns the project the source reads from.
+func (gl *GitLabSource) String() string {
+	return fmt.Sprintf("%s/projects/%s", gl.client.baseURL, gl.project)
+}
+
+// projectURL returns the API URL of a path below the project.
+func (gl *GitLabSource) projectURL(path string) string {
+	return gl.client.URL("/projects/" + url.PathEscape(gl.project) + path)
+}
+
+// refQuery returns the ref the project is read at as a query parameter with the given name, prefixed with "&",
+// or an empty string for the default branch.
+func (gl *GitLabSource) refQuery(name string) string {
+	if ref := gl.Ref(); ref != "" {
+		return "&" + name + "=" + url.QueryEscape(ref)
+	}
+	return ""
+}
+
+// List retrieves every file at the ref the project is read from, following the pages of the recursive tree listing.
+func (gl *GitLabSource) List(ctx context.Context) ([]SourceFile, error) {
+	var files []SourceFile
+
+	for page := "1"; page != ""; {
+		treeURL := gl.projectURL("/repository/tree?recursive=true&per_page=100&page=" + url.QueryEscape(page) + gl.refQuery("ref"))
+		log.Printf("fetching content from: %s", treeURL)
+
+		resp, err := gl.client.Get(ctx, treeURL, "application/json")
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []gitlabTreeEntry
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode repository tree of %s: %w", gl.project, err)
+		}
+
+		for _, entry := range entries {
+			if entry.Type == "blob" {
+				files = append(files, SourceFile{
+					Name: entry.Name,
+					Path: entry.Path,
+					SHA:  entry.ID,
+				})
+			}
+		}
+
+		page = resp.Header.Get("X-Next-Page")
+	}
+
+	return files, nil
+}
+
+// Revision returns the SHA of the latest commit at the ref the project is read from, or an empty string if the
+// project has no commits.
+func (gl *GitLabSource) Revision(ctx context.Context) (string, error) {
+	resp, err := gl.client.Get(ctx, gl.projectURL("/repository/commits?per_page=1"+gl.refQuery("ref_name")), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var commits []struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil {
+		return "", fmt.Errorf("failed to decode commits of %s: %w", gl.project, err)
+	}
+
+	if len(commits) == 0 {
+		return "", nil
+	}
+
+	return commits[0].ID, nil
+}
+
+// LatestRelease returns the tag of the most recently released release of the project, or an empty string if
+// there is none.
+func (gl *GitLabSource) LatestRelease(ctx context.Context) (string, error) {
+	resp, err := gl.client.Get(ctx, gl.projectURL("/releases?per_page=1&order_by=released_at&sort=desc"), "application/json")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var releases []struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return "", fmt.Errorf("failed to decode releases of %s: %w", gl.project, err)
+	}
+
+	if len(releases) == 0 {
+		return "", nil
+	}
+
+	return releases[0].TagName, nil
+}
+
+// Fetch retrieves the raw content of the file at the given path, at the ref the project is read from.
+func (gl *GitLabSource) Fetch(ctx context.Context, path string) (string, error) {
+	ref := gl.Ref()
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	resp, err := gl.client.Get(ctx, gl.projectURL("/repository/files/"+url.PathEscape(path)+"/raw?ref="+url.QueryEscape(ref)), "text/plain")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// forgetRef drops the client's cached responses for the given ref.
+func (gl *GitLabSource) forgetRef(ref string) {
+	gl.client.ForgetRef(ref)
+}
@@ -0,0 +1,95 @@
+package contentmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+)
+
+// httpManifestName is the file listing the content served by an HTTP source, relative to its base URL.
+const httpManifestName = "index.json"
+
+// HTTPSource reads Markdown files from any web server or object storage bucket. The files to read are listed in an
+// index.json manifest next to them, either as an array of paths or as an array of {"path", "sha"} objects.
+// A "sha" must be the Git blob SHA of the file, as printed by git hash-object, since it is compared with the blob SHA
+// of the content read before; files listed with any other hash are fetched again on every refresh.
+type HTTPSource struct {
+	client  *APIClient
+	baseURL string
+}
+
+// NewHTTPSource initializes and returns a pointer to an HTTPSource serving the files below baseURL.
+// Requests are sent through the given client, so unchanged files are requested conditionally.
+func NewHTTPSource(client *APIClient, baseURL string) *HTTPSource {
+	return &HTTPSource{
+		client:  client,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// String returns the URL the source reads from.
+func (hs *HTTPSource) String() string {
+	return hs.baseURL
+}
+
+// List retrieves the manifest and returns the files it lists.
+func (hs *HTTPSource) List(ctx context.Context) ([]SourceFile, error) {
+	manifestURL := hs.baseURL + "/" + httpManifestName
+	log.Printf("fetching content from: %s", manifestURL)
+
+	resp, err := hs.client.Get(ctx, manifestURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var raw []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", manifestURL, err)
+	}
+
+	files := make([]SourceFile, 0, len(raw))
+	for _, item := range raw {
+		var file SourceFile
+
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			file.Path = name
+		} else {
+			var entry struct {
+				Path string `json:"path"`
+				SHA  string `json:"sha"`
+			}
+			if err := json.Unmarshal(item, &entry); err != nil {
+				return nil, fmt.Errorf("invalid entry in %s: %s", manifestURL, item)
+			}
+			file.Path, file.SHA = entry.Path, entry.SHA
+		}
+
+		file.Path = strings.TrimPrefix(path.Clean("/"+file.Path), "/")
+		file.Name = path.Base(file.Path)
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// Fetch retrieves the file at the given path below the base URL.
+func (hs *HTTPSource) Fetch(ctx context.Context, file string) (string, error) {
+	resp, err := hs.client.Get(ctx, hs.baseURL+"/"+strings.TrimPrefix(path.Clean("/"+file), "/"), "text/markdown, text/plain, */*")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
@@ -0,0 +1,125 @@
+package contentmanager
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalSource reads Markdown files from a directory on the local filesystem, e.g. a checked-out content repository.
+type LocalSource struct {
+	dir string
+}
+
+// NewLocalSource initializes and returns a pointer to a LocalSource for the given directory.
+// Returns an error if the directory does not exist.
+func NewLocalSource(dir string) (*LocalSource, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open content directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("content path %s is not a directory", abs)
+	}
+
+	return &LocalSource{dir: abs}, nil
+}
+
+// String returns the directory the source reads from.
+func (ls *LocalSource) String() string {
+	return ls.dir
+}
+
+// Dir returns the absolute path of the directory the source reads from.
+func (ls *LocalSource) Dir() string {
+	return ls.dir
+}
+
+// List retrieves every file in the directory tree, skipping hidden directories such as .git.
+func (ls *LocalSource) List(ctx context.Context) ([]SourceFile, error) {
+	var files []SourceFile
+
+	err := filepath.WalkDir(ls.dir, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			if full != ls.dir && isHiddenName(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(ls.dir, full)
+		if err != nil {
+			return err
+		}
+
+		file := SourceFile{
+			Name: entry.Name(),
+			Path: filepath.ToSlash(rel),
+		}
+		if info, err := entry.Info(); err == nil {
+			file.Size = int(info.Size())
+		}
+
+		files = append(files, file)
+		return nil
+	})
+
+	return files, err
+}
+
+// isHiddenName reports whether a file or directory name is hidden, e.g. ".git".
+func isHiddenName(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// Fetch reads the file at the given path, relative to the root of the directory.
+func (ls *LocalSource) Fetch(ctx context.Context, path string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	full, err := ls.resolve(path)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// resolve converts a source-relative path to a filesystem path, rejecting paths that escape the directory.
+func (ls *LocalSource) resolve(path string) (string, error) {
+	full := filepath.Join(ls.dir, filepath.FromSlash(path))
+
+	rel, err := filepath.Rel(ls.dir, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %s is outside of the content directory", path)
+	}
+
+	return full, nil
+}
@@ -3,58 +3,36 @@ package contentmanager
 import (
 	"bytes"
 	"log"
+	"net/url"
+	"path"
 
 	"github.com/spf13/viper"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 
 	// "regexp"
 	"strings"
 	"time"
 )
 
-// parseMarkdown parses a Markdown string into a Post struct, extracting front matter and converting content to HTML.
-func parseMarkdown(content string) (Post, error) {
+// parseMarkdown parses a Markdown string into a Document struct, extracting front matter and converting content to HTML.
+func parseMarkdown(content string) (Document, error) {
 	fm, body, err := parseFrontMatter([]byte(content))
 	if err != nil {
-		return Post{}, err
+		return Document{}, err
 	}
 
-	output, err := markdownToHtml([]byte(body))
+	output, err := markdownToHtml([]byte(body), "")
 	if err != nil {
-		return Post{}, err
+		return Document{}, err
 	}
 
-	return Post{
-		ID:          fm.ID,
-		Date:        fm.Date,
-		DisplayDate: fm.Date.Format(time.RFC3339),
-		Title:       fm.Title,
-		Author:      fm.Author,
-		Summary:     fm.Summary,
-		Content:     output,
-		RawContent:  body,
-		Slug:        fm.Slug,
-		Tags:        fm.Tags,
-		Published:   fm.Published,
-	}, nil
-}
-
-// parseCheatsheetMarkdown parses a Markdown string into a Cheatsheet struct by extracting frontmatter and converting content to HTML.
-// Returns the parsed Cheatsheet or an error if parsing or conversion fails.
-func parseCheatsheetMarkdown(content string) (Cheatsheet, error) {
-	fm, body, err := parseCheatsheetFrontMatter([]byte(content))
-	if err != nil {
-		return Cheatsheet{}, err
-	}
-
-	output, err := markdownToHtml([]byte(body))
-	if err != nil {
-		return Cheatsheet{}, err
-	}
-
-	return Cheatsheet{
+	return Document{
 		ID:          fm.ID,
 		Date:        fm.Date,
 		DisplayDate: fm.Date.Format(time.RFC3339),
@@ -64,6 +42,7 @@ func parseCheatsheetMarkdown(content string) (Cheatsheet, error) {
 		Content:     output,
 		RawContent:  body,
 		Slug:        fm.Slug,
+		Category:    fm.Category,
 		Tags:        fm.Tags,
 		Published:   fm.Published,
 	}, nil
@@ -94,35 +73,11 @@ func parseFrontMatter(markdown []byte) (FrontMatter, string, error) {
 	return fm, parts[2], nil
 }
 
-// parseCheatsheetFrontMatter extracts and parses the frontmatter YAML from cheatsheet Markdown content.
-// Returns the parsed frontmatter, the remaining Markdown content, and an error if parsing fails.
-func parseCheatsheetFrontMatter(markdown []byte) (CheatsheetFrontMatter, string, error) {
-	parts := strings.SplitN(string(markdown), "---", 3)
-	if len(parts) < 3 {
-		log.Printf("No frontmatter found in cheatsheet markdown content (length: %d)", len(markdown))
-		return CheatsheetFrontMatter{}, string(markdown), nil
-	}
-
-	v := viper.New()
-	v.SetConfigType("yaml")
-	if err := v.ReadConfig(bytes.NewBufferString(parts[1])); err != nil {
-		log.Printf("Failed to parse YAML cheatsheet frontmatter: %v", err)
-		return CheatsheetFrontMatter{}, "", err
-	}
-
-	var fm CheatsheetFrontMatter
-	if err := v.Unmarshal(&fm); err != nil {
-		log.Printf("Failed to unmarshal cheatsheet frontmatter into struct: %v", err)
-		return CheatsheetFrontMatter{}, "", err
-	}
-
-	return fm, parts[2], nil
-}
-
 // markdownToHtml converts a Markdown input to HTML, using Goldmark with extensions like GFM, Linkify, and unsafe rendering.
-// Returns the converted HTML string or an error if the conversion process fails.
-func markdownToHtml(markdown []byte) (string, error) {
-	md := goldmark.New(
+// If linkBase is set, relative link and image destinations are prefixed with it, e.g. "diagram.png" becomes
+// "intro/diagram.png". Returns the converted HTML string or an error if the conversion process fails.
+func markdownToHtml(markdown []byte, linkBase string) (string, error) {
+	options := []goldmark.Option{
 		goldmark.WithExtensions(
 			extension.GFM,
 		),
@@ -132,7 +87,13 @@ func markdownToHtml(markdown []byte) (string, error) {
 		goldmark.WithExtensions(
 			extension.Linkify,
 		),
-	)
+	}
+	if linkBase != "" {
+		options = append(options, goldmark.WithParserOptions(
+			parser.WithASTTransformers(util.Prioritized(relativeLinks(linkBase), 100)),
+		))
+	}
+	md := goldmark.New(options...)
 
 	var buf bytes.Buffer
 	if err := md.Convert(markdown, &buf); err != nil {
@@ -150,3 +111,37 @@ func markdownToHtml(markdown []byte) (string, error) {
 
 	return output, nil
 }
+
+// relativeLinks rewrites relative link and image destinations to be relative to a base path.
+type relativeLinks string
+
+// Transform implements parser.ASTTransformer.
+func (base relativeLinks) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.Link:
+			n.Destination = base.rewrite(n.Destination)
+		case *ast.Image:
+			n.Destination = base.rewrite(n.Destination)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// rewrite prefixes a destination with the base path unless it is absolute, has a scheme or is a fragment.
+func (base relativeLinks) rewrite(destination []byte) []byte {
+	dest := string(destination)
+	if dest == "" || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "?") {
+		return destination
+	}
+
+	if u, err := url.Parse(dest); err != nil || u.Scheme != "" || u.Host != "" {
+		return destination
+	}
+
+	return []byte(path.Join(string(base), strings.TrimPrefix(dest, "./")))
+}
@@ -0,0 +1,64 @@
+package contentmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// RefSource is implemented by sources that read a Git repository at a configurable ref, such as a branch or tag.
+type RefSource interface {
+	// Ref returns the ref the source reads from, or an empty string for the repository's default branch.
+	Ref() string
+	// SetRef changes the ref the source reads from. Files already listed may be fetched at the new ref.
+	SetRef(ref string)
+}
+
+// ReleaseSource is implemented by sources that can look up the releases of their repository.
+type ReleaseSource interface {
+	// LatestRelease returns the tag of the latest published release, or an empty string if there is none.
+	LatestRelease(ctx context.Context) (string, error)
+}
+
+// StatusSource is implemented by sources that can set the status of a commit of their repository, which the
+// repository's host shows next to the commit and the pull requests containing it.
+type StatusSource interface {
+	// SetStatus sets the status of the commit with the given SHA.
+	SetStatus(ctx context.Context, sha string, status CommitStatus) error
+}
+
+// CommitStatus is the status of a commit, e.g. the state of a pull request preview.
+type CommitStatus struct {
+	State       string `json:"state"`                 // pending, success, failure or error
+	TargetURL   string `json:"target_url,omitempty"`  // Link the status points to
+	Description string `json:"description,omitempty"` // Short summary, at most 140 characters
+	Context     string `json:"context"`               // Label distinguishing the status from others on the commit
+}
+
+// refCache is implemented by sources that cache API responses per ref, so the responses for a ref that is no
+// longer read can be dropped instead of waiting to be evicted.
+type refCache interface {
+	forgetRef(ref string)
+}
+
+// trackedRef holds the ref a repository source reads from. It is safe for concurrent use,
+// since pollers check revisions while refreshes fetch files.
+type trackedRef struct {
+	mu  sync.RWMutex
+	ref string
+}
+
+// Ref returns the ref the source reads from, or an empty string for the repository's default branch.
+func (r *trackedRef) Ref() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.ref
+}
+
+// SetRef changes the ref the source reads from.
+func (r *trackedRef) SetRef(ref string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ref = ref
+}
@@ -0,0 +1,65 @@
+package contentmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// refreshGuard makes sure refreshes of a collection never run concurrently, whether they are started by the poller,
+// a webhook or an operator. Full refreshes are coalesced in the style of singleflight: callers arriving while a
+// refresh runs share a single follow-up refresh, which starts once the running one has finished and so sees every
+// change made before any of them called.
+type refreshGuard struct {
+	running sync.Mutex // Held while a refresh of the collection runs
+
+	mu      sync.Mutex
+	pending *refreshCall // Full refresh waiting for the running refresh, shared by its callers
+}
+
+// refreshCall is a full refresh shared by every caller that asked for it while it was pending.
+type refreshCall struct {
+	done   chan struct{}
+	report *RefreshReport
+	err    error
+}
+
+// exclusive runs fn once no other refresh of the collection is running.
+func (g *refreshGuard) exclusive(fn func()) {
+	g.running.Lock()
+	defer g.running.Unlock()
+
+	fn()
+}
+
+// full runs the full refresh fn once no other refresh of the collection is running, or waits for the result of
+// the full refresh already waiting to run. Callers that give up waiting get the context's error; the shared
+// refresh still runs for the others.
+func (g *refreshGuard) full(ctx context.Context, fn func() (*RefreshReport, error)) (*RefreshReport, error) {
+	g.mu.Lock()
+	if call := g.pending; call != nil {
+		g.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.report, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	g.pending = call
+	g.mu.Unlock()
+
+	g.exclusive(func() {
+		// Callers arriving from now on may miss changes this refresh has already listed, so they wait for the next one
+		g.mu.Lock()
+		g.pending = nil
+		g.mu.Unlock()
+
+		call.report, call.err = fn()
+	})
+	close(call.done)
+
+	return call.report, call.err
+}
@@ -0,0 +1,90 @@
+package contentmanager
+
+import (
+	"time"
+)
+
+// FileStatus describes what a refresh did with a single source file.
+type FileStatus string
+
+const (
+	FileUpdated   FileStatus = "updated"   // Fetched and parsed, now served
+	FileSkipped   FileStatus = "skipped"   // Fetched and parsed, but not served because it is unpublished or has no slug
+	FileUnchanged FileStatus = "unchanged" // Not fetched because its content has not changed since the last refresh
+	FileRemoved   FileStatus = "removed"   // Removed from the source and no longer served
+	FileFailed    FileStatus = "failed"    // Could not be fetched or parsed; the previous version, if any, is still served
+)
+
+// FileReport records the outcome of refreshing a single source file.
+type FileReport struct {
+	Path         string        `json:"path"`
+	Status       FileStatus    `json:"status"`
+	Error        string        `json:"error,omitempty"`
+	KeptPrevious bool          `json:"kept_previous,omitempty"` // Whether a failed file's previous version is still served
+	Duration     time.Duration `json:"duration"`
+}
+
+// RefreshReport summarizes a refresh of a collection, with the outcome of every file it processed.
+type RefreshReport struct {
+	Collection string        `json:"collection"`
+	Ref        string        `json:"ref,omitempty"`      // Ref the source was read at, empty for the default branch
+	Revision   string        `json:"revision,omitempty"` // Commit SHA served after the refresh, empty if unknown
+	StartedAt  time.Time     `json:"started_at"`
+	Duration   time.Duration `json:"duration"`
+	Files      []FileReport  `json:"files"`
+}
+
+// newRefreshReport starts a report for a refresh of the named collection.
+func newRefreshReport(collection string) *RefreshReport {
+	return &RefreshReport{
+		Collection: collection,
+		StartedAt:  time.Now(),
+	}
+}
+
+// add records the outcome of a file processed since start.
+func (r *RefreshReport) add(path string, status FileStatus, start time.Time) {
+	r.Files = append(r.Files, FileReport{
+		Path:     path,
+		Status:   status,
+		Duration: time.Since(start),
+	})
+}
+
+// fail records a file processed since start that could not be refreshed, and whether its previous version was kept.
+func (r *RefreshReport) fail(path string, err error, keptPrevious bool, start time.Time) {
+	r.Files = append(r.Files, FileReport{
+		Path:         path,
+		Status:       FileFailed,
+		Error:        err.Error(),
+		KeptPrevious: keptPrevious,
+		Duration:     time.Since(start),
+	})
+}
+
+// finish records the total duration of the refresh.
+func (r *RefreshReport) finish() {
+	r.Duration = time.Since(r.StartedAt)
+}
+
+// Count returns the number of files with the given status.
+func (r *RefreshReport) Count(status FileStatus) int {
+	count := 0
+	for _, file := range r.Files {
+		if file.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the reports of the files that could not be refreshed.
+func (r *RefreshReport) Failed() []FileReport {
+	var failed []FileReport
+	for _, file := range r.Files {
+		if file.Status == FileFailed {
+			failed = append(failed, file)
+		}
+	}
+	return failed
+}
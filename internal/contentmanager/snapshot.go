@@ -0,0 +1,186 @@
+package contentmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot format. Snapshots written with a different version are not loaded.
+const SnapshotVersion = 1
+
+// Snapshot is a serializable copy of the parsed content of every collection, used to serve the last known good
+// content immediately at startup instead of waiting for the sources.
+type Snapshot struct {
+	Version     int                           `json:"version"`
+	Site        string                        `json:"site,omitempty"`   // Name of the site the content belongs to
+	Bundle      string                        `json:"bundle,omitempty"` // ID of the embedded bundle the content started from
+	CreatedAt   time.Time                     `json:"createdAt"`
+	Collections map[string]CollectionSnapshot `json:"collections"`
+}
+
+// CollectionSnapshot holds the parsed documents of a collection and the state of its source files.
+type CollectionSnapshot struct {
+	Documents []Document     `json:"documents"`
+	Files     []SnapshotFile `json:"files"`
+	Assets    []string       `json:"assets,omitempty"`   // Source paths of the files that are not documents
+	Ref       string         `json:"ref,omitempty"`      // Ref the source was read at, empty for the default branch
+	Revision  string         `json:"revision,omitempty"` // Source revision the content was read at, empty if unknown
+}
+
+// SnapshotFile records the SHA of a document file and the slug of the published document parsed from it, if any.
+type SnapshotFile struct {
+	Path string `json:"path"`
+	SHA  string `json:"sha"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// Snapshot returns a copy of the collection's documents and file state.
+func (c *Collection) Snapshot() CollectionSnapshot {
+	c.RLock()
+	defer c.RUnlock()
+
+	snapshot := CollectionSnapshot{
+		Documents: make([]Document, 0, len(c.documents)),
+		Files:     make([]SnapshotFile, 0, len(c.files)),
+		Ref:       c.Ref(),
+		Revision:  c.served,
+	}
+
+	for _, doc := range c.documents {
+		snapshot.Documents = append(snapshot.Documents, doc)
+	}
+
+	for path, state := range c.files {
+		snapshot.Files = append(snapshot.Files, SnapshotFile{Path: path, SHA: state.SHA, Slug: state.Slug})
+	}
+
+	for path := range c.assets {
+		snapshot.Assets = append(snapshot.Assets, path)
+	}
+
+	return snapshot
+}
+
+// Restore replaces the collection's documents and file state with the content of the snapshot.
+// Later refreshes only fetch files whose SHA differs from the snapshot.
+func (c *Collection) Restore(snapshot CollectionSnapshot) {
+	documents := make(map[string]Document, len(snapshot.Documents))
+	for _, doc := range snapshot.Documents {
+		documents[doc.Slug] = doc
+	}
+
+	files := make(map[string]fileState, len(snapshot.Files))
+	for _, file := range snapshot.Files {
+		files[file.Path] = fileState{SHA: file.SHA, Slug: file.Slug}
+	}
+
+	// Snapshots written before assets were recorded leave them unknown until the next refresh
+	var assets map[string]bool
+	if snapshot.Assets != nil {
+		assets = make(map[string]bool, len(snapshot.Assets))
+		for _, path := range snapshot.Assets {
+			assets[path] = true
+		}
+	}
+
+	c.Lock()
+	c.documents = documents
+	c.files = files
+	c.assets = assets
+	c.served = snapshot.Revision
+	c.Unlock()
+
+	// Keep reading the release the content was pinned to. Other collections read the configured ref, which may
+	// have changed since the snapshot was taken; their next refresh replaces the content.
+	if source, ok := c.source.(RefSource); ok && c.config.FollowReleases && snapshot.Ref != "" {
+		source.SetRef(snapshot.Ref)
+	}
+}
+
+// Snapshot returns a snapshot of every registered collection.
+func (cm *ContentManager) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Version:     SnapshotVersion,
+		CreatedAt:   time.Now().UTC(),
+		Collections: make(map[string]CollectionSnapshot),
+	}
+
+	for _, collection := range cm.All() {
+		snapshot.Collections[collection.Name()] = collection.Snapshot()
+	}
+
+	return snapshot
+}
+
+// Restore loads the snapshot into the registered collections with matching names and returns their names.
+// Collections missing from the snapshot are left untouched.
+func (cm *ContentManager) Restore(snapshot Snapshot) []string {
+	var restored []string
+
+	for _, collection := range cm.All() {
+		if data, exists := snapshot.Collections[collection.Name()]; exists {
+			collection.Restore(data)
+			restored = append(restored, collection.Name())
+		}
+	}
+
+	return restored
+}
+
+// SaveSnapshot writes the snapshot as JSON to the given path. The file is replaced atomically,
+// so a crash while saving never leaves a truncated snapshot behind.
+func SaveSnapshot(path string, snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot reads a snapshot written by SaveSnapshot from the given path.
+// Returns an error if the file cannot be read or was written with a different snapshot version.
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return DecodeSnapshot(data)
+}
+
+// DecodeSnapshot parses a JSON snapshot, returning an error if it was written with a different snapshot version.
+func DecodeSnapshot(data []byte) (Snapshot, error) {
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	if snapshot.Version != SnapshotVersion {
+		return Snapshot{}, fmt.Errorf("snapshot version %d is not supported, expected %d", snapshot.Version, SnapshotVersion)
+	}
+
+	return snapshot, nil
+}
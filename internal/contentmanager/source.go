@@ -0,0 +1,153 @@
+package contentmanager
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// Source provides the raw Markdown files of a content collection, e.g. from a GitHub repository or a local directory.
+type Source interface {
+	// List returns every file in the source, including files in subdirectories.
+	List(ctx context.Context) ([]SourceFile, error)
+	// Fetch returns the content of the file at the given path, relative to the root of the source.
+	// Fetch may be called concurrently.
+	Fetch(ctx context.Context, path string) (string, error)
+	// String returns a human-readable description of the source for logging.
+	String() string
+}
+
+// Versioned is implemented by sources that can cheaply report the revision of their content, such as the SHA of
+// the latest commit of a repository, so a collection that hasn't changed is not refreshed.
+type Versioned interface {
+	// Revision returns the current revision of the source, or an empty string if it cannot be determined.
+	Revision(ctx context.Context) (string, error)
+}
+
+// SourceFile describes a file available from a Source.
+type SourceFile struct {
+	Name string // Base name of the file
+	Path string // Path of the file relative to the root of the source, using forward slashes
+	Size int    // Size of the file in bytes
+	SHA  string // Git blob SHA of the file content, empty if the source cannot provide it cheaply
+}
+
+// gitBlobSHA returns the Git blob SHA-1 of the content, the same value GitHub reports as a file's "sha".
+func gitBlobSHA(content string) string {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", len(content))
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Source types supported by NewSource.
+const (
+	SourceGitHub        = "github"
+	SourceGitHubArchive = "github-archive"
+	SourceGitLab        = "gitlab"
+	SourceGitea         = "gitea"
+	SourceHTTP          = "http"
+	SourceLocal         = "local"
+)
+
+// SourceConfig describes where a collection reads its content from.
+type SourceConfig struct {
+	Type       string // SourceGitHub (default), SourceGitHubArchive, SourceGitLab, SourceGitea, SourceHTTP or SourceLocal
+	RepoOwner  string // Account, organisation or GitLab group that owns the content repository
+	RepoName   string // Repository holding the Markdown files
+	Dir        string // Local directory holding the Markdown files, used by SourceLocal
+	ArchiveURL string // Overrides the tarball URL used by SourceGitHubArchive, e.g. to read from a mirror
+	BaseURL    string // API base URL, e.g. a GitHub Enterprise "https://ghe.example.com/api/v3", or the root URL of SourceHTTP
+	Token      string // Access token, defaults to the provider's GITHUB_TOKEN, GITLAB_TOKEN or GITEA_TOKEN
+	Ref        string // Branch, tag or commit SHA to read, empty for the repository's default branch
+
+	GitHubAuth GitHubAuthConfig // Authenticates SourceGitHub and SourceGitHubArchive as a GitHub App instead of with GITHUB_TOKEN
+}
+
+// NewSource creates the Source described by the given configuration, returning an error if it is incomplete
+// or sets a ref the source cannot read. API-backed sources use a client from the given pool, or a client of their
+// own if clients is nil.
+func NewSource(config SourceConfig, clients *ClientPool) (Source, error) {
+	if clients == nil {
+		clients = NewClientPool("", "")
+	}
+
+	source, err := newSource(config, clients)
+	if err != nil || config.Ref == "" {
+		return source, err
+	}
+
+	// Archives downloaded from a configured URL are always read as they are
+	refs, ok := source.(RefSource)
+	if archive, isArchive := source.(*ArchiveSource); !ok || isArchive && archive.repoName == "" {
+		return nil, fmt.Errorf("%s source %s cannot read ref %s", config.Type, source, config.Ref)
+	}
+	refs.SetRef(config.Ref)
+
+	return source, nil
+}
+
+// newSource implements NewSource, without setting the ref.
+func newSource(config SourceConfig, clients *ClientPool) (Source, error) {
+	switch config.Type {
+	case SourceGitHub, "":
+		if config.RepoOwner == "" || config.RepoName == "" {
+			return nil, fmt.Errorf("github source requires a repository owner and name")
+		}
+		client, err := clients.clientFor(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewGitHubSource(client, config.RepoOwner, config.RepoName), nil
+	case SourceGitHubArchive:
+		client, err := clients.clientFor(config)
+		if err != nil {
+			return nil, err
+		}
+		if config.ArchiveURL != "" {
+			return NewArchiveSource(client, config.ArchiveURL), nil
+		}
+		if config.RepoOwner == "" || config.RepoName == "" {
+			return nil, fmt.Errorf("github-archive source requires a repository owner and name or an archive URL")
+		}
+		source := NewArchiveSource(client, githubArchiveURL(client, config.RepoOwner, config.RepoName))
+		source.repoOwner, source.repoName = config.RepoOwner, config.RepoName
+		return source, nil
+	case SourceGitLab:
+		if config.RepoOwner == "" || config.RepoName == "" {
+			return nil, fmt.Errorf("gitlab source requires a project namespace and name")
+		}
+		client, err := clients.clientFor(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewGitLabSource(client, config.RepoOwner+"/"+config.RepoName), nil
+	case SourceGitea:
+		if config.RepoOwner == "" || config.RepoName == "" {
+			return nil, fmt.Errorf("gitea source requires a repository owner and name")
+		}
+		client, err := clients.clientFor(config)
+		if err != nil {
+			return nil, err
+		}
+		// Gitea's contents API is compatible with GitHub's
+		return NewGitHubSource(client, config.RepoOwner, config.RepoName), nil
+	case SourceHTTP:
+		if config.BaseURL == "" {
+			return nil, fmt.Errorf("http source requires a base URL")
+		}
+		client, err := clients.clientFor(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewHTTPSource(client, config.BaseURL), nil
+	case SourceLocal:
+		if config.Dir == "" {
+			return nil, fmt.Errorf("local source requires a directory")
+		}
+		return NewLocalSource(config.Dir)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", config.Type)
+	}
+}
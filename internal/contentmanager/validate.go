@@ -0,0 +1,132 @@
+package contentmanager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// Problem describes an issue found while validating a collection's content.
+type Problem struct {
+	Path    string // Source path of the file the problem was found in
+	Message string
+}
+
+// String formats the problem as "path: message".
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidateOptions configures the checks performed by Validate.
+type ValidateOptions struct {
+	KnownTags []string // Tags documents may use; any tag is accepted if empty
+}
+
+// validatedDocument is a successfully parsed document together with the file it was read from.
+type validatedDocument struct {
+	doc  Document
+	path string
+}
+
+// Validate parses every document in the collection's source using the same path as RefreshContent,
+// without changing the collection, and reports every problem found instead of stopping at the first one.
+// It checks for front matter errors, missing and duplicate slugs, missing dates, unknown tags, links to
+// documents of the collection that do not exist, and unpublished documents linked from published ones.
+func (c *Collection) Validate(ctx context.Context, options ValidateOptions) ([]Problem, error) {
+	files, err := c.source.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s content: %v", c.config.Name, err)
+	}
+
+	var problems []Problem
+	var docs []validatedDocument
+	bySlug := make(map[string][]validatedDocument)
+
+	knownTags := make(map[string]bool, len(options.KnownTags))
+	for _, tag := range options.KnownTags {
+		knownTags[tag] = true
+	}
+
+	for _, file := range files {
+		if !c.isDocumentPath(file.Path) {
+			continue
+		}
+
+		doc, _, err := c.loadDocument(ctx, file.Path)
+		if err != nil {
+			problems = append(problems, Problem{Path: file.Path, Message: err.Error()})
+			continue
+		}
+
+		if doc.Slug == "" {
+			problems = append(problems, Problem{Path: file.Path, Message: "missing slug"})
+		} else {
+			bySlug[doc.Slug] = append(bySlug[doc.Slug], validatedDocument{doc: doc, path: file.Path})
+		}
+
+		if doc.Date.IsZero() {
+			problems = append(problems, Problem{Path: file.Path, Message: "missing date"})
+		}
+
+		if len(knownTags) > 0 {
+			for _, tag := range doc.Tags {
+				if !knownTags[tag] {
+					problems = append(problems, Problem{Path: file.Path, Message: fmt.Sprintf("unknown tag %q", tag)})
+				}
+			}
+		}
+
+		docs = append(docs, validatedDocument{doc: doc, path: file.Path})
+	}
+
+	for slug, matches := range bySlug {
+		if len(matches) < 2 {
+			continue
+		}
+		for _, match := range matches {
+			problems = append(problems, Problem{Path: match.path, Message: fmt.Sprintf("duplicate slug %q (used by %d files)", slug, len(matches))})
+		}
+	}
+
+	problems = append(problems, c.validateLinks(docs, bySlug)...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Path < problems[j].Path
+	})
+
+	return problems, nil
+}
+
+// validateLinks reports links from published documents to documents of the same collection
+// that do not exist or are not published.
+func (c *Collection) validateLinks(docs []validatedDocument, bySlug map[string][]validatedDocument) []Problem {
+	var problems []Problem
+	pattern := internalLinkPattern(c.config.RoutePrefix)
+
+	for _, source := range docs {
+		if !source.doc.Published {
+			continue
+		}
+
+		for _, match := range pattern.FindAllStringSubmatch(source.doc.RawContent, -1) {
+			slug := match[1]
+			targets, exists := bySlug[slug]
+
+			switch {
+			case !exists:
+				problems = append(problems, Problem{Path: source.path, Message: fmt.Sprintf("broken link to %s/%s", c.config.RoutePrefix, slug)})
+			case !targets[0].doc.Published:
+				problems = append(problems, Problem{Path: source.path, Message: fmt.Sprintf("links to unpublished document %s/%s (%s)", c.config.RoutePrefix, slug, targets[0].path)})
+			}
+		}
+	}
+
+	return problems
+}
+
+// internalLinkPattern matches Markdown and HTML links to documents under the route prefix, e.g. "](/posts/slug)"
+// or `href="/posts/slug"`, capturing the slug.
+func internalLinkPattern(routePrefix string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:\]\(\s*|href=["'])` + regexp.QuoteMeta(routePrefix) + `/([^/\s)"'#?]+)`)
+}
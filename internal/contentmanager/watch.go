@@ -0,0 +1,175 @@
+package contentmanager
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long Watch waits for further events on a file before re-parsing it.
+// Editors often write a file in several steps (truncate, write, rename), which should result in a single reload.
+const watchDebounce = 100 * time.Millisecond
+
+// Watch watches the local directory tree backing the collection and re-parses only the Markdown files that change.
+// onChange is called with the source path of every file that was updated or removed. Watch blocks until ctx is done.
+// Returns an error if the collection is not backed by a LocalSource or the directory cannot be watched.
+func (c *Collection) Watch(ctx context.Context, onChange func(path string)) error {
+	local, ok := c.source.(*LocalSource)
+	if !ok {
+		return fmt.Errorf("%s collection is not backed by a local directory", c.config.Name)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watchTree(watcher, local.Dir()); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", local.Dir(), err)
+	}
+
+	log.Printf("Watching %s for %s changes", local.Dir(), c.config.Name)
+
+	var mu sync.Mutex
+	pending := make(map[string]*time.Timer)
+
+	// schedule re-parses the file at full once no further events arrived for it within watchDebounce
+	schedule := func(full string) {
+		rel, err := filepath.Rel(local.Dir(), full)
+		if err != nil {
+			return
+		}
+		path := filepath.ToSlash(rel)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if timer, exists := pending[path]; exists {
+			timer.Stop()
+		}
+		pending[path] = time.AfterFunc(watchDebounce, func() {
+			mu.Lock()
+			delete(pending, path)
+			mu.Unlock()
+
+			c.applyWatchEvent(ctx, local, path, onChange)
+		})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			for _, timer := range pending {
+				timer.Stop()
+			}
+			mu.Unlock()
+			return nil
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("Watch error for %s: %v", c.config.Name, err)
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			// Watch directories created after startup, e.g. a new per-post folder. A directory moved or checked
+			// out into the tree arrives as a single event, so the files already inside it are loaded as well.
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watchTree(watcher, event.Name); err != nil {
+						log.Printf("Failed to watch %s: %v", event.Name, err)
+					}
+					if err := walkFiles(event.Name, schedule); err != nil {
+						log.Printf("Failed to load %s: %v", event.Name, err)
+					}
+					continue
+				}
+			}
+
+			schedule(event.Name)
+		}
+	}
+}
+
+// watchTree adds the directory and all of its non-hidden subdirectories to the watcher.
+func watchTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if full != root && isHiddenName(entry.Name()) {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(full)
+	})
+}
+
+// walkFiles calls fn with every file below root, skipping hidden files and directories.
+func walkFiles(root string, fn func(full string)) error {
+	return filepath.WalkDir(root, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if full != root && isHiddenName(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.IsDir() {
+			fn(full)
+		}
+		return nil
+	})
+}
+
+// applyWatchEvent re-parses the document file at the given path, or removes its document if the file no longer exists.
+func (c *Collection) applyWatchEvent(ctx context.Context, local *LocalSource, path string, onChange func(path string)) {
+	_, err := os.Stat(filepath.Join(local.Dir(), filepath.FromSlash(path)))
+	missing := os.IsNotExist(err)
+
+	if !c.isDocumentPath(path) {
+		c.setAsset(path, !missing)
+		// A removed or renamed directory takes the documents below it along
+		if missing && c.RemoveDir(path) {
+			log.Printf("Removed %s directory: %s", c.config.Name, path)
+			onChange(path)
+		}
+		return
+	}
+
+	if missing {
+		log.Printf("Removed %s file: %s", c.config.Name, path)
+		c.RemoveFile(path)
+		onChange(path)
+		return
+	}
+
+	if err := c.RefreshFile(ctx, path); err != nil {
+		log.Printf("Failed to reload %s: %v", path, err)
+		return
+	}
+
+	log.Printf("Reloaded %s file: %s", c.config.Name, path)
+	onChange(path)
+}
@@ -0,0 +1,47 @@
+package livereload
+
+import (
+	"sync"
+)
+
+// Broker fans out reload notifications to every connected browser tab.
+type Broker struct {
+	sync.Mutex
+	clients map[chan struct{}]struct{}
+}
+
+// NewBroker initializes and returns a pointer to a Broker without subscribers.
+func NewBroker() *Broker {
+	return &Broker{
+		clients: make(map[chan struct{}]struct{}),
+	}
+}
+
+// Subscribe registers a new client and returns the channel it receives reload notifications on,
+// along with a function that unregisters the client.
+func (b *Broker) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.Lock()
+	b.clients[ch] = struct{}{}
+	b.Unlock()
+
+	return ch, func() {
+		b.Lock()
+		delete(b.clients, ch)
+		b.Unlock()
+	}
+}
+
+// Notify asks every subscribed client to reload. Clients that already have a pending notification are skipped.
+func (b *Broker) Notify() {
+	b.Lock()
+	defer b.Unlock()
+
+	for ch := range b.clients {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -1,20 +1,94 @@
 package site
 
-const (
+import (
+	"context"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+)
+
+// Metadata describes the site a page is rendered for, in the HTML header of every page and the sitemap.
+type Metadata struct {
 	// Description provides a description metadata property in the HTML header for SEO.
-	Description string = "Jeremy specializes in Cloud Engineering, DevOps, Education and Consulting"
+	Description string
 	// URL is the base URL of the site in the HTML header for SEO.
-	URL string = "https://jgn.dev"
+	URL string
 	// Author is the author metadata property in the HTML header for SEO.
-	Author string = "Jeremy Novak"
+	Author string
 	// Generator is the metadata property for the site in the HTML header for SEO.
-	Generator string = "jgn.dev"
-	// PostRepoOwner should be set to the name of the GitHub account hosting the posts repo.
-	PostRepoOwner string = "jgndev"
-	// PostRepoName should be set to the name of the GitHub repo that has the posts in Markdown format.
-	PostRepoName string = "posts"
-	// CheatsheetRepoOwner should be set to the name of the GitHub account hosting the cheatsheets repo.
-	CheatsheetRepoOwner string = "jgndev"
-	// CheatsheetRepoName should be set to the name of the GitHub repo that has the cheatsheets in Markdown format.
-	CheatsheetRepoName string = "cheatsheets"
-)
+	Generator string
+	// Image is the absolute URL of the image shown when pages of the site are shared.
+	Image string
+	// Twitter is the site's Twitter handle in the Twitter card, e.g. "@jgndev", or empty to leave it out.
+	Twitter string
+	// LiveReload includes the live reload script in pages, so they reload when watched content changes.
+	LiveReload bool
+	// BasePath prefixes the links between pages, e.g. "/preview/jgndev/posts/12" for pages served under a pull request preview.
+	BasePath string
+	// NoIndex asks search engines not to index pages or follow their links, e.g. for previews.
+	NoIndex bool
+}
+
+// Path returns the path a link to the page at p, e.g. "/posts", points to: p below the base path.
+func (m Metadata) Path(p string) string {
+	return m.BasePath + p
+}
+
+// Default is the metadata of pages rendered without a configured site.
+var Default = Metadata{
+	Description: "Jeremy specializes in Cloud Engineering, DevOps, Education and Consulting",
+	URL:         "https://jgn.dev",
+	Author:      "Jeremy Novak",
+	Generator:   "jgn.dev",
+	Image:       "https://jgn.dev/public/og-image.jpg",
+	Twitter:     "@jgndev",
+}
+
+// New returns the metadata of the site described by the site section of the runtime configuration.
+// The share image defaults to the site's /public/og-image.jpg.
+func New(cfg config.SiteConfig) Metadata {
+	image := cfg.Image
+	if image == "" {
+		image = cfg.URL + "/public/og-image.jpg"
+	}
+
+	return Metadata{
+		Description: cfg.Description,
+		URL:         cfg.URL,
+		Author:      cfg.Author,
+		Generator:   cfg.Name,
+		Image:       image,
+		Twitter:     cfg.Twitter,
+	}
+}
+
+// Collection holds the routes of a content collection, which pages listing or searching its documents link to.
+type Collection struct {
+	Prefix string // Route prefix of the collection, e.g. "/posts"
+	Search string // Path of the collection's search page, e.g. "/search"
+}
+
+// Document returns the path of the collection's document with the given slug.
+func (c Collection) Document(slug string) string {
+	return c.Prefix + "/" + slug
+}
+
+type contextKey struct{}
+
+// WithMetadata returns a copy of ctx carrying the metadata of the site pages are rendered for.
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, metadata)
+}
+
+// FromContext returns the metadata of the site pages are rendered for, or Default if ctx doesn't carry any.
+func FromContext(ctx context.Context) Metadata {
+	if metadata, ok := ctx.Value(contextKey{}).(Metadata); ok {
+		return metadata
+	}
+
+	return Default
+}
+
+// Path returns the path a link to the page at p points to for the site pages are rendered for.
+func Path(ctx context.Context, p string) string {
+	return FromContext(ctx).Path(p)
+}
@@ -0,0 +1,8 @@
+// Live reload for local content authoring. Only included when the server runs in watch mode.
+(function () {
+    const source = new EventSource('/_livereload');
+
+    source.addEventListener('reload', function () {
+        window.location.reload();
+    });
+})();
@@ -256,6 +256,7 @@ deploy_to_cloud_run() {
         --allow-unauthenticated \
         --set-env-vars="$ENV_VARS" \
         --cpu=1 \
+        --no-cpu-throttling \
         --memory=512Mi \
         --min-instances=0 \
         --max-instances=10 \
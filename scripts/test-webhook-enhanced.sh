@@ -24,6 +24,7 @@ test_webhook() {
     # Sample webhook payload
     PAYLOAD='{
   "ref": "refs/heads/main",
+  "after": "'$(openssl rand -hex 20)'",
   "commits": [
     {
       "added": ["'$test_file'"],
@@ -47,6 +48,8 @@ test_webhook() {
     # Send the webhook request
     RESPONSE=$(curl -s -X POST "$WEBHOOK_URL" \
       -H "Content-Type: application/json" \
+      -H "X-GitHub-Event: push" \
+      -H "X-GitHub-Delivery: $(uuidgen 2>/dev/null || openssl rand -hex 16)" \
       -H "X-Hub-Signature-256: sha256=$SIGNATURE" \
       -d "$PAYLOAD" \
       -w "\nHTTP_STATUS:%{http_code}")
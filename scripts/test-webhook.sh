@@ -12,6 +12,7 @@ fi
 # Sample webhook payload (what GitHub would send)
 PAYLOAD='{
   "ref": "refs/heads/main",
+  "after": "'$(openssl rand -hex 20)'",
   "commits": [
     {
       "added": ["new-test-post.md"],
@@ -35,6 +36,8 @@ echo ""
 
 curl -X POST http://localhost:8080/webhook/github \
   -H "Content-Type: application/json" \
+  -H "X-GitHub-Event: push" \
+  -H "X-GitHub-Delivery: $(uuidgen 2>/dev/null || openssl rand -hex 16)" \
   -H "X-Hub-Signature-256: sha256=$SIGNATURE" \
   -d "$PAYLOAD" \
   -w "\nHTTP Status: %{http_code}\n"
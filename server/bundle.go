@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/jgndev/jgn.dev/internal/application"
+	"github.com/jgndev/jgn.dev/internal/bundle"
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+)
+
+// runBundle implements the "bundle" command. It fetches and parses the content of every collection of a site
+// and writes it as a snapshot that the next build embeds, so the image contains the exact reviewed content.
+func runBundle(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("bundle", flag.ExitOnError)
+	out := flags.String("out", bundle.Path, "path of the content bundle to write")
+	siteName := flags.String("site", "", "name of the site to bundle, defaults to the first configured site")
+	flags.Parse(args)
+
+	cfg = siteConfig(cfg, *siteName)
+
+	cm, err := application.NewContentManager(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up content: %v", err)
+	}
+
+	for _, collection := range cm.All() {
+		report, err := collection.RefreshContent(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", collection.Name(), err)
+		}
+
+		// The bundle must contain exactly the reviewed content, so any file that fails to load is fatal
+		if failed := report.Failed(); len(failed) > 0 {
+			for _, file := range failed {
+				log.Printf("Failed to load %s: %s", file.Path, file.Error)
+			}
+			log.Fatalf("Failed to load %d %s files", len(failed), collection.Name())
+		}
+		log.Printf("Bundled %d %s", len(collection.GetAll()), collection.Name())
+	}
+
+	snapshot := cm.Snapshot()
+	snapshot.Site = cfg.Site.Name
+
+	if err := contentmanager.SaveSnapshot(*out, snapshot); err != nil {
+		log.Fatalf("Failed to write content bundle: %v", err)
+	}
+
+	log.Printf("Wrote content bundle to %s", *out)
+}
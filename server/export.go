@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jgndev/jgn.dev/internal/application"
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/labstack/echo/v4"
+)
+
+// runExport implements the "export" command. It renders every page a site serves into a directory of static
+// files, together with the public assets, so the site can be mirrored to object storage or a CDN.
+// The content is loaded from the sources first; the export fails if any file cannot be loaded.
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	out := flags.String("out", "dist", "directory to write the static site to")
+	siteName := flags.String("site", "", "name of the site to export, defaults to the first configured site")
+	flags.Parse(args)
+
+	cfg = siteConfig(cfg, *siteName)
+
+	app, err := application.Load(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load content: %w", err)
+	}
+	defer app.Close()
+	e := newServer(app)
+
+	routes, err := exportRoutes(app)
+	if err != nil {
+		return fmt.Errorf("failed to list routes: %w", err)
+	}
+	for _, route := range routes {
+		if err := exportRoute(e, route, *out); err != nil {
+			return fmt.Errorf("failed to export %s: %w", route, err)
+		}
+	}
+
+	if err := copyDir("public", filepath.Join(*out, "public")); err != nil {
+		return fmt.Errorf("failed to copy public assets: %w", err)
+	}
+
+	log.Printf("Exported %d pages to %s", len(routes), *out)
+	return nil
+}
+
+// exportRoutes returns every GET route of the application that renders without request input,
+// including the assets of documents read from per-post folders.
+func exportRoutes(app *application.Application) ([]string, error) {
+	routes := []string{"/", "/about", "/sitemap.xml", "/favicon.ico", "/robots.txt"}
+
+	for _, collection := range app.ContentManager.All() {
+		config := collection.Config()
+		routes = append(routes, config.RoutePrefix, config.SearchPath)
+
+		for _, doc := range collection.GetAll() {
+			routes = append(routes, config.RoutePrefix+"/"+doc.Slug)
+		}
+
+		assets, err := collection.Assets(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for slug, names := range assets {
+			for _, name := range names {
+				routes = append(routes, config.RoutePrefix+"/"+slug+"/"+name)
+			}
+		}
+	}
+
+	return routes, nil
+}
+
+// exportRoute renders a single route through the Echo instance and writes the response body to the output directory.
+// Routes without a file extension are written as index.html files, so "/posts/foo" becomes "posts/foo/index.html".
+func exportRoute(e *echo.Echo, route, out string) error {
+	req := httptest.NewRequest(http.MethodGet, route, nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("status %d", rec.Code)
+	}
+
+	name := strings.TrimPrefix(route, "/")
+	if path.Ext(name) == "" {
+		name = path.Join(name, "index.html")
+	}
+
+	target := filepath.Join(out, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(target, rec.Body.Bytes(), 0o644)
+}
+
+// copyDir recursively copies the regular files of the src directory to dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, full)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		return copyFile(full, target)
+	})
+}
+
+// copyFile copies a single file from src to dst, replacing dst if it exists.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
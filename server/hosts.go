@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jgndev/jgn.dev/internal/application"
+	"github.com/jgndev/jgn.dev/internal/config"
+)
+
+// hostRouter dispatches requests to the server of the site configured for their Host header, so several sites
+// can be served by the same process. Each site has its own Echo instance, collections and content sources.
+type hostRouter struct {
+	hosts    map[string]http.Handler    // Server of each configured host name
+	fallback http.Handler               // Serves hosts no site claims, nil to respond with 404
+	apps     []*application.Application // Applications of the sites that started
+}
+
+// newHostRouter starts the application of every configured site and returns a router dispatching to them.
+// Sites are started concurrently. A site that fails to start is logged and answers its hosts with 503, so it
+// doesn't take the other sites down; the router is only unusable if no site could be started.
+func newHostRouter(cfg *config.Config) (*hostRouter, bool) {
+	sites := cfg.SiteConfigs()
+	handlers := make([]http.Handler, len(sites))
+	apps := make([]*application.Application, len(sites))
+
+	var wg sync.WaitGroup
+	for i, siteConfig := range sites {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			app, err := application.New(siteConfig)
+			if err != nil {
+				log.Printf("Failed to start site %s: %v", siteConfig.Site.Name, err)
+				handlers[i] = http.HandlerFunc(siteUnavailable)
+				return
+			}
+
+			handlers[i] = newServer(app)
+			apps[i] = app
+			log.Printf("Serving site %s on %s", siteConfig.Site.Name, describeHosts(siteConfig.Site.Hosts))
+		}()
+	}
+	wg.Wait()
+
+	router := &hostRouter{hosts: make(map[string]http.Handler)}
+	for i, siteConfig := range sites {
+		// A single site serves every host, as do sites without hosts when hosting several
+		if len(sites) == 1 || len(siteConfig.Site.Hosts) == 0 {
+			router.fallback = handlers[i]
+		}
+
+		for _, host := range siteConfig.Site.Hosts {
+			router.hosts[strings.ToLower(host)] = handlers[i]
+		}
+	}
+
+	for _, app := range apps {
+		if app != nil {
+			router.apps = append(router.apps, app)
+		}
+	}
+
+	return router, len(router.apps) > 0
+}
+
+// Close stops the background work of every site and waits for it to finish.
+func (router *hostRouter) Close() {
+	var wg sync.WaitGroup
+	for _, app := range router.apps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.Close()
+		}()
+	}
+	wg.Wait()
+}
+
+// ServeHTTP routes the request to the server of the site for its host, ignoring the port.
+func (router *hostRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if handler, exists := router.hosts[strings.ToLower(host)]; exists {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	if router.fallback != nil {
+		router.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+// siteUnavailable answers requests for a site that failed to start.
+func siteUnavailable(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "site unavailable", http.StatusServiceUnavailable)
+}
+
+// describeHosts returns the host names a site is served on, for logging.
+func describeHosts(hosts []string) string {
+	if len(hosts) == 0 {
+		return "all other hosts"
+	}
+
+	return strings.Join(hosts, ", ")
+}
+
+// siteConfig returns the configuration of the site with the given name, or of the first configured site
+// if name is empty. Used by the commands that work on a single site.
+func siteConfig(cfg *config.Config, name string) *config.Config {
+	sites := cfg.SiteConfigs()
+	if name == "" {
+		return sites[0]
+	}
+
+	for _, siteConfig := range sites {
+		if siteConfig.Site.Name == name {
+			return siteConfig
+		}
+	}
+
+	log.Fatalf("Unknown site: %s", name)
+	return nil
+}
@@ -108,142 +108,290 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/jgndev/jgn.dev/internal/application"
+	"github.com/jgndev/jgn.dev/internal/config"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-// cacheMiddleware adds appropriate cache headers for static assets
-func cacheMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		path := c.Request().URL.Path
+// shutdownTimeout bounds how long in-flight requests may take to finish when the server shuts down.
+const shutdownTimeout = 15 * time.Second
+
+// cacheMiddleware adds appropriate cache headers for static assets, using the configured max-age of each kind of asset
+func cacheMiddleware(policy map[string]time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			path := c.Request().URL.Path
+
+			// Only apply caching to static assets
+			if !strings.HasPrefix(path, "/public/") &&
+				path != "/favicon.ico" &&
+				path != "/robots.txt" &&
+				path != "/sitemap.xml" {
+				return next(c)
+			}
+
+			// Determine cache duration based on file type
+			var kind string
+
+			switch {
+			case strings.HasSuffix(path, ".woff2") || strings.HasSuffix(path, ".woff") ||
+				strings.HasSuffix(path, ".ttf") || strings.HasSuffix(path, ".otf"):
+				// Fonts: 1 year by default (they rarely change)
+				kind = config.AssetFonts
+
+			case strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".js"):
+				// CSS and JS: 30 days by default (may change with updates)
+				kind = config.AssetScripts
+
+			case strings.HasSuffix(path, ".ico") || strings.HasSuffix(path, ".png") ||
+				strings.HasSuffix(path, ".jpg") || strings.HasSuffix(path, ".jpeg") ||
+				strings.HasSuffix(path, ".gif") || strings.HasSuffix(path, ".svg") ||
+				strings.HasSuffix(path, ".webp"):
+				// Images: 30 days by default
+				kind = config.AssetImages
+
+			case strings.HasSuffix(path, ".txt"):
+				// Text files like robots.txt: 1 day by default (might need updates)
+				kind = config.AssetText
+
+			case strings.HasSuffix(path, ".xml"):
+				// XML files like sitemap: 1 hour by default (for SEO freshness)
+				kind = config.AssetXML
+
+			default:
+				// Default for other static assets: 7 days by default
+				kind = config.AssetDefault
+			}
+
+			maxAge := policy[kind]
+
+			// Set cache headers
+			if maxAge > 0 {
+				maxAgeSeconds := int(maxAge.Seconds())
+				c.Response().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAgeSeconds))
+				c.Response().Header().Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
+				// Add ETag for better cache validation
+				c.Response().Header().Set("ETag", `"static-asset"`)
+			}
 
-		// Only apply caching to static assets
-		if !strings.HasPrefix(path, "/public/") &&
-			path != "/favicon.ico" &&
-			path != "/robots.txt" &&
-			path != "/sitemap.xml" {
 			return next(c)
 		}
-
-		// Determine cache duration based on file type
-		var maxAge time.Duration
-
-		switch {
-		case strings.HasSuffix(path, ".woff2") || strings.HasSuffix(path, ".woff") ||
-			strings.HasSuffix(path, ".ttf") || strings.HasSuffix(path, ".otf"):
-			// Fonts: 1 year (they rarely change)
-			maxAge = 365 * 24 * time.Hour
-
-		case strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".js"):
-			// CSS and JS: 30 days (may change with updates)
-			maxAge = 30 * 24 * time.Hour
-
-		case strings.HasSuffix(path, ".ico") || strings.HasSuffix(path, ".png") ||
-			strings.HasSuffix(path, ".jpg") || strings.HasSuffix(path, ".jpeg") ||
-			strings.HasSuffix(path, ".gif") || strings.HasSuffix(path, ".svg") ||
-			strings.HasSuffix(path, ".webp"):
-			// Images: 30 days
-			maxAge = 30 * 24 * time.Hour
-
-		case strings.HasSuffix(path, ".txt"):
-			// Text files like robots.txt: 1 day (might need updates)
-			maxAge = 24 * time.Hour
-
-		case strings.HasSuffix(path, ".xml"):
-			// XML files like sitemap: 1 hour (for SEO freshness)
-			maxAge = 1 * time.Hour
-
-		default:
-			// Default for other static assets: 7 days
-			maxAge = 7 * 24 * time.Hour
-		}
-
-		// Set cache headers
-		if maxAge > 0 {
-			maxAgeSeconds := int(maxAge.Seconds())
-			c.Response().Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAgeSeconds))
-			c.Response().Header().Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
-			// Add ETag for better cache validation
-			c.Response().Header().Set("ETag", `"static-asset"`)
-		}
-
-		return next(c)
 	}
 }
 
 // validateEnvironment checks critical environment variables and logs warnings
-func validateEnvironment() {
+func validateEnvironment(cfg *config.Config) {
 	githubToken := os.Getenv("GITHUB_TOKEN")
-	if githubToken == "" {
+	if appID := os.Getenv("GITHUB_APP_ID"); appID != "" {
+		log.Printf("✓ GITHUB_APP_ID configured - authenticating as GitHub App %s installation", appID)
+	} else if githubToken == "" {
 		log.Println("WARNING: GITHUB_TOKEN not set - GitHub API requests will be rate limited (60/hour vs 5000/hour)")
 		log.Println("         This may cause webhook failures during high traffic periods")
 		log.Println("         Set GITHUB_TOKEN environment variable with a GitHub personal access token")
 	} else {
 		log.Println("✓ GITHUB_TOKEN configured - GitHub API rate limit: 5000/hour")
 	}
-	
+
+	if cfg.Features.Previews {
+		switch {
+		case !cfg.Features.Webhooks:
+			log.Println("WARNING: Pull request previews enabled but webhooks disabled - no previews will be built")
+		case cfg.Previews.Secret == "":
+			log.Println("WARNING: previews.secret not set - preview links stop working when the server restarts")
+		default:
+			log.Println("✓ Pull request previews enabled")
+		}
+	}
+
+	if !cfg.Features.Webhooks {
+		log.Println("Webhooks disabled - content is only refreshed on startup")
+		return
+	}
+
+	hasDefault := false
+	for _, secrets := range cfg.Webhook.Secrets {
+		repo := secrets.Repo
+		if repo == "" {
+			repo = "repositories without their own secrets"
+			hasDefault = true
+		}
+
+		switch {
+		case secrets.File != "":
+			if _, err := os.Stat(secrets.File); err != nil {
+				log.Printf("WARNING: Webhook secrets file of %s cannot be read - its webhooks will be rejected: %v", repo, err)
+			} else {
+				log.Printf("✓ Webhook secrets of %s read from %s", repo, secrets.File)
+			}
+		case secrets.Previous != "":
+			log.Printf("✓ Webhook secrets configured for %s - previous secret still accepted, remove it once senders use the current one", repo)
+		default:
+			log.Printf("✓ Webhook secret configured for %s", repo)
+		}
+	}
+
+	// Repositories without their own secrets use the site's secret, then the webhook.secrets default
 	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if webhookSecret == "" {
-		log.Println("WARNING: GITHUB_WEBHOOK_SECRET not set - webhook endpoint will reject all requests")
-		log.Println("         Set GITHUB_WEBHOOK_SECRET environment variable to enable webhook functionality")
-	} else {
-		log.Println("✓ GITHUB_WEBHOOK_SECRET configured - webhook endpoint secured")
+	for _, siteConfig := range cfg.SiteConfigs() {
+		name := siteConfig.Site.Name
+		switch {
+		case siteConfig.Site.WebhookSecret != "":
+			log.Printf("✓ Webhook secret configured for %s - webhook endpoint secured", name)
+		case hasDefault:
+			log.Printf("✓ webhook.secrets default configured for %s - webhook endpoint secured", name)
+		case webhookSecret == "":
+			log.Printf("WARNING: GITHUB_WEBHOOK_SECRET not set - webhook endpoint of %s will reject all requests", name)
+			log.Println("         Set GITHUB_WEBHOOK_SECRET environment variable or the site's webhook_secret to enable webhook functionality")
+		default:
+			log.Printf("✓ GITHUB_WEBHOOK_SECRET configured - webhook endpoint of %s secured", name)
+		}
 	}
 }
 
-func main() {
-	// Validate critical environment variables
-	validateEnvironment()
-	
+// newServer creates the Echo instance serving the application, with middleware, static assets and routes registered.
+func newServer(app *application.Application) *echo.Echo {
 	e := echo.New()
 
 	// Configure middleware
 	e.Use(middleware.Recover())
+	e.Use(app.SiteContext)
+	e.Use(app.ContentRevision)
 	e.Use(middleware.CORS())
 
 	// Configure Gzip with skipper to exclude sitemap.xml
 	e.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
 		Skipper: func(c echo.Context) bool {
-			// Skip gzip for sitemap.xml to avoid XML parsing issues
-			return c.Path() == "/sitemap.xml"
+			// Skip gzip for sitemap.xml to avoid XML parsing issues, and for the
+			// live reload event stream so events are delivered immediately
+			return c.Path() == "/sitemap.xml" || c.Path() == "/_livereload"
 		},
 	}))
 
 	// Apply cache middleware to all requests (it will only set headers for static assets)
-	e.Use(cacheMiddleware)
+	e.Use(cacheMiddleware(app.Config.Cache.MaxAge))
 
 	// Static assets bundling
 	e.Static("/public", "public")
 	e.File("/favicon.ico", "public/img/favicon.ico")
-	e.File("/robots.txt", "public/txt/robots.txt")
-
-	app := application.New()
+	e.GET("/robots.txt", app.RobotsTxt)
 
-	// Routes
+	// Routes, also listed in the config package's reservedRoutes so collections cannot collide with them
 	e.GET("/", app.Home)
-	e.GET("/posts", app.PostsList)
-	e.GET("/search", app.SearchPage)
-	e.GET("/posts/:slug", app.PostDetail)
-	e.GET("/cheatsheets", app.CheatsheetsList)
-	e.GET("/cheatsheets/search", app.CheatsheetSearchPage)
-	e.GET("/cheatsheets/:slug", app.CheatsheetDetail)
 	e.GET("/about", app.About)
 
+	// Content collections (list, search and detail pages)
+	app.RegisterCollectionRoutes(e)
+
 	// Sitemap
-	e.GET("/sitemap.xml", app.SitemapXML)
+	if app.Config.Features.Sitemap {
+		e.GET("/sitemap.xml", app.SitemapXML)
+	}
 
 	// Webhook for automatic content updates
-	e.POST("/webhook/github", app.WebhookHandler)
+	if app.Config.Features.Webhooks {
+		e.POST("/webhook/github", app.WebhookHandler)
+		e.POST("/webhook/gitlab", app.GitLabWebhookHandler)
+		e.POST("/webhook/gitea", app.GiteaWebhookHandler)
+		e.GET("/webhook/jobs/:id", app.RefreshJobStatus)
+	}
+
+	// Pull request previews, opened with the signed link posted as the status of the pull request's head commit
+	if app.Config.Features.Previews {
+		preview := e.Group("/preview/:owner/:repo/:number", app.PreviewAccess)
+		preview.GET("", app.Home)
+		preview.GET("/", app.Home)
+		preview.GET("/about", app.About)
+		app.RegisterCollectionRoutes(preview)
+	}
+
+	// Live reload events for local content authoring (features.watch)
+	e.GET("/_livereload", app.LiveReload)
+
+	// Admin endpoints, authenticated with ADMIN_TOKEN
+	admin := e.Group("/admin", app.AdminAuth)
+	admin.GET("/refresh", app.RefreshStatus)
+	admin.GET("/vars", echo.WrapHandler(expvar.Handler()))
+	if app.Config.Features.Webhooks {
+		admin.GET("/webhook/deliveries", app.WebhookDeliveries)
+		admin.GET("/webhook/deliveries/:id", app.WebhookDelivery)
+		admin.POST("/webhook/deliveries/:id/replay", app.ReplayWebhookDelivery)
+	}
+	if app.Config.Features.Previews {
+		admin.GET("/previews", app.Previews)
+	}
+
+	return e
+}
+
+func main() {
+	// Load the site configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "bundle":
+			runBundle(cfg, os.Args[2:])
+			return
+		case "export":
+			if err := runExport(cfg, os.Args[2:]); err != nil {
+				log.Fatalf("Export failed: %v", err)
+			}
+			return
+		case "validate":
+			runValidate(cfg, os.Args[2:])
+			return
+		}
+	}
+
+	// Validate critical environment variables
+	validateEnvironment(cfg)
+
+	// Start every configured site, routed by the Host header of requests
+	router, ok := newHostRouter(cfg)
+	if !ok {
+		log.Fatalf("Failed to initialize application: no site could be started")
+	}
 
 	// Start the application
-	e.Logger.Fatal(e.Start(":8080"))
+	server := &http.Server{Addr: cfg.Server.Listen, Handler: router}
+	go func() {
+		log.Printf("Listening on %s", cfg.Server.Listen)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+	}()
+
+	// Shut down gracefully when the platform stops the container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	log.Println("Shutting down")
+
+	// Stop pollers, watchers and refreshes first, so webhook requests waiting on a refresh can finish
+	router.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down gracefully: %v", err)
+	}
 }
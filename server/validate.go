@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jgndev/jgn.dev/internal/config"
+	"github.com/jgndev/jgn.dev/internal/contentmanager"
+)
+
+// runValidate implements the "validate" command. It parses a local checkout of a collection's content with the
+// same rules the site uses and reports every problem found, exiting with a non-zero status if there are any,
+// so content repositories can run it in CI before a change reaches the site.
+func runValidate(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	name := flags.String("collection", "posts", "name of the collection the content belongs to")
+	dir := flags.String("dir", ".", "directory containing the collection's Markdown files")
+	tags := flags.String("tags", "", "comma-separated list of allowed tags")
+	tagsFile := flags.String("tags-file", "", "file listing allowed tags, one per line")
+	siteName := flags.String("site", "", "name of the site the collection belongs to, defaults to the first configured site")
+	flags.Parse(args)
+
+	cfg = siteConfig(cfg, *siteName)
+
+	index := slices.IndexFunc(cfg.Collections, func(collection config.CollectionConfig) bool {
+		return collection.Name == *name
+	})
+	if index < 0 {
+		log.Fatalf("Unknown collection: %s", *name)
+	}
+
+	contentConfig := cfg.ContentConfig(cfg.Collections[index])
+	contentConfig.Source = contentmanager.SourceConfig{
+		Type: contentmanager.SourceLocal,
+		Dir:  *dir,
+	}
+
+	collection, err := contentmanager.NewCollection(contentConfig, nil)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", *dir, err)
+	}
+
+	knownTags, err := allowedTags(*tags, *tagsFile)
+	if err != nil {
+		log.Fatalf("Failed to read allowed tags: %v", err)
+	}
+
+	problems, err := collection.Validate(context.Background(), contentmanager.ValidateOptions{KnownTags: knownTags})
+	if err != nil {
+		log.Fatalf("Failed to validate %s: %v", *dir, err)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		fmt.Printf("%d problems found in %s\n", len(problems), *dir)
+		os.Exit(1)
+	}
+
+	fmt.Printf("No problems found in %s\n", *dir)
+}
+
+// allowedTags combines the tags given on the command line with those listed in the tags file.
+// Blank lines and lines starting with "#" in the file are ignored.
+func allowedTags(list, file string) ([]string, error) {
+	var tags []string
+
+	for _, tag := range strings.Split(list, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	if file == "" {
+		return tags, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tags = append(tags, line)
+	}
+
+	return tags, scanner.Err()
+}